@@ -0,0 +1,200 @@
+// Package cli implements the kparse command line tool.
+//
+// It can be used directly through the cmd/kparse binary or embedded on a
+// small Go program that registers its config types, so the `schema`
+// subcommand can generate schemas for them:
+//
+//	func main() {
+//		cli.Register("server", &server.Config{})
+//		cli.Main()
+//	}
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/teamcollab-net/kparse"
+)
+
+var registry sync.Map
+
+// Register makes the struct pointed by structPtr available
+// to the `schema` and `validate` subcommands under the given name.
+func Register(name string, structPtr any) {
+	registry.Store(name, structPtr)
+}
+
+// Main runs the command line tool with the arguments of the
+// current process and exits with the appropriate status code.
+func Main() {
+	os.Exit(Run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+const usage = `usage: kparse <command> [arguments]
+
+commands:
+  validate  validate config files against a JSON Schema
+  schema    print the JSON Schema of a registered type
+  fmt       normalize the key order and formatting of config files
+  keygen    generate a key for encrypting config values
+  encrypt   encrypt a value in the ENC[...] format
+`
+
+// Run executes the subcommand described by args writing its results
+// to stdout and its errors to stderr, it returns the exit status code.
+func Run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "validate":
+		err = runValidate(args[1:], stdout, stderr)
+	case "schema":
+		err = runSchema(args[1:], stdout, stderr)
+	case "fmt":
+		err = runFmt(args[1:], stdout, stderr)
+	case "keygen":
+		err = runKeygen(args[1:], stdout, stderr)
+	case "encrypt":
+		err = runEncrypt(args[1:], os.Stdin, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "kparse: unknown command '%s'\n\n%s", args[0], usage)
+		return 2
+	}
+
+	if err == errValidationFailed {
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "kparse %s: %s\n", args[0], err)
+		return 1
+	}
+
+	return 0
+}
+
+func runSchema(args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("schema", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	tagName := flags.String("tag", "yaml", "the struct tag used for reading the keys")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: kparse schema [-tag yaml] <type>")
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected exactly one type name, registered types are: %s", registeredNames())
+	}
+
+	rawSchema, err := registeredSchema(flags.Arg(0), *tagName)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(stdout, "%s\n", rawSchema)
+	return err
+}
+
+func registeredSchema(name string, tagName string) ([]byte, error) {
+	structPtr, found := registry.Load(name)
+	if !found {
+		return nil, fmt.Errorf("no type registered with name '%s', registered types are: %s", name, registeredNames())
+	}
+
+	return kparse.JSONSchema(structPtr, tagName)
+}
+
+func registeredNames() string {
+	var names []string
+	registry.Range(func(key, _ any) bool {
+		names = append(names, key.(string))
+		return true
+	})
+
+	if len(names) == 0 {
+		return "(none)"
+	}
+
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func runFmt(args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	write := flags.Bool("w", false, "write the result to the source file instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: kparse fmt [-w] <file>...")
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least one file")
+	}
+
+	for _, path := range flags.Args() {
+		format, err := fileFormat(path)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		formatted, err := kparse.Format(file, format)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		if *write {
+			err = os.WriteFile(path, formatted, 0o644)
+		} else {
+			_, err = stdout.Write(formatted)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func fileFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml", nil
+	case ".json":
+		return "json", nil
+	case ".toml":
+		return "toml", nil
+	}
+
+	return "", fmt.Errorf("%s: unsupported file extension, expected one of: .yaml, .yml, .json or .toml", path)
+}
@@ -0,0 +1,249 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teamcollab-net/kparse"
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+type testConfig struct {
+	SecretKey int `yaml:"secretKey" validate:"required"`
+
+	Address struct {
+		Street string `yaml:"street" validate:"required"`
+		City   string `yaml:"city"`
+	} `yaml:"address"`
+
+	MaxRetries int      `yaml:"maxRetries" validate:">0,<=10"`
+	Desc       string   `yaml:"desc" validate:"len>=10"`
+	Domains    []string `yaml:"domains" validate:"len>=1"`
+
+	Timeout time.Duration `yaml:"timeout" default:"10s"`
+
+	Pin   int    `yaml:"pin" secret:"true" validate:"<1000"`
+	Token string `yaml:"token" secret:"true"`
+	Auth  struct {
+		Key string `yaml:"key"`
+	} `yaml:"auth" secret:"true"`
+}
+
+func TestValidate(t *testing.T) {
+	Register("testConfig", &testConfig{})
+
+	tests := []struct {
+		desc           string
+		fileName       string
+		file           string
+		expectedStatus int
+		expectedOutput string
+	}{
+		{
+			desc:     "should accept valid yaml files",
+			fileName: "config.yaml",
+			file: `secretKey: 42
+address:
+  street: fakeStreet
+maxRetries: 3
+desc: a long enough description
+domains: [example.com]
+timeout: 5s
+`,
+			expectedStatus: 0,
+			expectedOutput: "",
+		},
+		{
+			desc:     "should report all errors of yaml files with their positions",
+			fileName: "config.yaml",
+			file: `secretKey: "abc"
+address:
+  city: 3
+maxRetries: 11
+desc: short
+domains: []
+`,
+			expectedStatus: 1,
+			expectedOutput: "{dir}/config.yaml:1:12: secretKey: expected integer but got the string \"abc\"\n" +
+				"{dir}/config.yaml:3:9: address.city: expected string but got 3\n" +
+				"{dir}/config.yaml:3:3: address: missing required key 'street'\n" +
+				"{dir}/config.yaml:4:13: maxRetries: expected a value <= 10 but got 11\n" +
+				"{dir}/config.yaml:5:7: desc: expected a length of at least 10 but got 5\n" +
+				"{dir}/config.yaml:6:10: domains: expected at least 1 items but got 0\n",
+		},
+		{
+			desc:     "should report errors of json files with their positions",
+			fileName: "config.json",
+			file: `{
+  "address": {"street": "fakeStreet"},
+  "maxRetries": 0
+}
+`,
+			expectedStatus: 1,
+			expectedOutput: "{dir}/config.json:3:17: maxRetries: expected a value > 0 but got 0\n" +
+				"{dir}/config.json:1:1: missing required key 'secretKey'\n",
+		},
+		{
+			desc:     "should accept valid toml files",
+			fileName: "config.toml",
+			file: `secretKey = 42
+maxRetries = 3
+desc = "a long enough description"
+domains = ["example.com"]
+timeout = "5s"
+
+[address]
+street = "fakeStreet"
+`,
+			expectedStatus: 0,
+			expectedOutput: "",
+		},
+		{
+			desc:     "should report all errors of toml files with their positions",
+			fileName: "config.toml",
+			file: `secretKey = "abc"
+maxRetries = 11
+desc = "short"
+domains = []
+
+[address]
+city = 3
+`,
+			expectedStatus: 1,
+			expectedOutput: "{dir}/config.toml:1:13: secretKey: expected integer but got the string \"abc\"\n" +
+				"{dir}/config.toml:2:14: maxRetries: expected a value <= 10 but got 11\n" +
+				"{dir}/config.toml:3:8: desc: expected a length of at least 10 but got 5\n" +
+				"{dir}/config.toml:4:11: domains: expected at least 1 items but got 0\n" +
+				"{dir}/config.toml:7:8: address.city: expected string but got 3\n" +
+				"{dir}/config.toml:6:2: address: missing required key 'street'\n",
+		},
+		{
+			desc:     "should leave the values of secrets out of the errors",
+			fileName: "config.yaml",
+			file: `secretKey: 42
+address:
+  street: fakeStreet
+pin: 4242
+token: 123456
+auth:
+  key: 987654
+`,
+			expectedStatus: 1,
+			expectedOutput: "{dir}/config.yaml:4:6: pin: expected a value < 1000\n" +
+				"{dir}/config.yaml:5:8: token: expected string but got a number\n" +
+				"{dir}/config.yaml:7:8: auth.key: expected string but got a number\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, test.fileName)
+			err := os.WriteFile(path, []byte(test.file), 0o644)
+			tt.AssertNoErr(t, err)
+
+			var stdout, stderr bytes.Buffer
+			status := Run([]string{"validate", "-type", "testConfig", path}, &stdout, &stderr)
+			tt.AssertEqual(t, stderr.String(), "")
+			tt.AssertEqual(t, status, test.expectedStatus)
+			tt.AssertEqual(t, stdout.String(), replaceDir(test.expectedOutput, dir))
+		})
+	}
+
+	t.Run("should validate against schema files", func(t *testing.T) {
+		dir := t.TempDir()
+		schemaPath := filepath.Join(dir, "schema.json")
+		configPath := filepath.Join(dir, "config.yaml")
+
+		var schema bytes.Buffer
+		status := Run([]string{"schema", "testConfig"}, &schema, os.Stderr)
+		tt.AssertEqual(t, status, 0)
+
+		err := os.WriteFile(schemaPath, schema.Bytes(), 0o644)
+		tt.AssertNoErr(t, err)
+		err = os.WriteFile(configPath, []byte("address: {}\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var stdout, stderr bytes.Buffer
+		status = Run([]string{"validate", "-schema", schemaPath, configPath}, &stdout, &stderr)
+		tt.AssertEqual(t, status, 1)
+		tt.AssertEqual(t, stdout.String(), replaceDir(
+			"{dir}/config.yaml:1:10: address: missing required key 'street'\n"+
+				"{dir}/config.yaml:1:1: missing required key 'secretKey'\n",
+			dir,
+		))
+	})
+
+	t.Run("should report unknown types", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		status := Run([]string{"schema", "notRegistered"}, &stdout, &stderr)
+		tt.AssertEqual(t, status, 1)
+		tt.AssertEqual(t, stderr.String(), "kparse schema: no type registered with name 'notRegistered', registered types are: testConfig\n")
+	})
+}
+
+func TestFmt(t *testing.T) {
+	t.Run("should write formatted files in place", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(path, []byte("b: 1\n# comment about a\na:\n    d: 2\n    c: 3\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var stdout, stderr bytes.Buffer
+		status := Run([]string{"fmt", "-w", path}, &stdout, &stderr)
+		tt.AssertEqual(t, stderr.String(), "")
+		tt.AssertEqual(t, status, 0)
+
+		formatted, err := os.ReadFile(path)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, string(formatted), "# comment about a\na:\n  c: 3\n  d: 2\nb: 1\n")
+	})
+
+	t.Run("should format toml files", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		err := os.WriteFile(path, []byte("b = 1\n\n# comment about a\n[a]\nd = 2\nc = 3\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var stdout, stderr bytes.Buffer
+		status := Run([]string{"fmt", path}, &stdout, &stderr)
+		tt.AssertEqual(t, stderr.String(), "")
+		tt.AssertEqual(t, status, 0)
+		tt.AssertEqual(t, stdout.String(), "b = 1\n\n# comment about a\n[a]\nc = 3\nd = 2\n")
+	})
+
+	t.Run("should reject unknown file extensions", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		status := Run([]string{"fmt", "config.ini"}, &stdout, &stderr)
+		tt.AssertEqual(t, status, 1)
+		tt.AssertEqual(t, stderr.String(), "kparse fmt: config.ini: unsupported file extension, expected one of: .yaml, .yml, .json or .toml\n")
+	})
+}
+
+func replaceDir(s string, dir string) string {
+	return strings.ReplaceAll(s, "{dir}", dir)
+}
+
+func TestEncrypt(t *testing.T) {
+	var key bytes.Buffer
+	status := Run([]string{"keygen"}, &key, os.Stderr)
+	tt.AssertEqual(t, status, 0)
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	err := os.WriteFile(keyFile, key.Bytes(), 0o600)
+	tt.AssertNoErr(t, err)
+
+	var stdout, stderr bytes.Buffer
+	status = Run([]string{"encrypt", "-key-file", keyFile, "fakePassword"}, &stdout, &stderr)
+	tt.AssertEqual(t, stderr.String(), "")
+	tt.AssertEqual(t, status, 0)
+
+	decodedKey, err := kparse.ReadKeyFile(keyFile)
+	tt.AssertNoErr(t, err)
+
+	plaintext, err := kparse.Decrypt(decodedKey, strings.TrimSpace(stdout.String()))
+	tt.AssertNoErr(t, err)
+	tt.AssertEqual(t, plaintext, "fakePassword")
+}
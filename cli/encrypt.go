@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/teamcollab-net/kparse"
+)
+
+func runKeygen(args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: kparse keygen")
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	key, err := kparse.GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(stdout, key)
+	return err
+}
+
+func runEncrypt(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("encrypt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	keyFile := flags.String("key-file", "", "path to a file containing the key generated by `kparse keygen`")
+	keyEnv := flags.String("key-env", kparse.DecryptionKeyEnvVar, "name of the env var containing the key, used when -key-file is not set")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: kparse encrypt [-key-file <file> | -key-env <name>] [value]")
+		fmt.Fprintln(stderr, "\nif the value is omitted it is read from stdin")
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return fmt.Errorf("expected at most one value to encrypt")
+	}
+
+	var key []byte
+	if *keyFile != "" {
+		key, err = kparse.ReadKeyFile(*keyFile)
+	} else {
+		key, err = kparse.DecodeKey(os.Getenv(*keyEnv))
+		if err != nil {
+			err = fmt.Errorf("invalid key on env var '%s': %w", *keyEnv, err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	value := flags.Arg(0)
+	if flags.NArg() == 0 {
+		input, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		value = strings.TrimSuffix(string(input), "\n")
+	}
+
+	encrypted, err := kparse.Encrypt(key, value)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(stdout, encrypted)
+	return err
+}
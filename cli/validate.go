@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/teamcollab-net/kparse"
+	"github.com/teamcollab-net/kparse/internal/tomlnode"
+	"gopkg.in/yaml.v3"
+)
+
+// errValidationFailed is returned after the validation errors were
+// already printed, so Run only needs to set the exit status code.
+var errValidationFailed = errors.New("validation failed")
+
+func runValidate(args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	schemaPath := flags.String("schema", "", "path to a JSON Schema file generated by kparse")
+	typeName := flags.String("type", "", "name of a registered type to generate the schema from")
+	tagName := flags.String("tag", "yaml", "the struct tag used for reading the keys of -type")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: kparse validate (-schema <file> | -type <name>) <file>...")
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if (*schemaPath == "") == (*typeName == "") {
+		flags.Usage()
+		return fmt.Errorf("expected exactly one of -schema or -type")
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least one file to validate")
+	}
+
+	var rawSchema []byte
+	if *schemaPath != "" {
+		rawSchema, err = os.ReadFile(*schemaPath)
+	} else {
+		rawSchema, err = registeredSchema(*typeName, *tagName)
+	}
+	if err != nil {
+		return err
+	}
+
+	var schema kparse.Schema
+	err = json.Unmarshal(rawSchema, &schema)
+	if err != nil {
+		return fmt.Errorf("error parsing schema: %w", err)
+	}
+
+	failed := false
+	for _, path := range flags.Args() {
+		errs, err := validateFile(path, &schema)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range errs {
+			fmt.Fprintf(stdout, "%s:%d:%d: %s\n", path, e.Line, e.Column, e.Message)
+		}
+		failed = failed || len(errs) > 0
+	}
+
+	if failed {
+		return errValidationFailed
+	}
+
+	return nil
+}
+
+type validationError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func validateFile(path string, schema *kparse.Schema) ([]validationError, error) {
+	format, err := fileFormat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Since JSON is a subset of YAML we can use the YAML parser for
+	// both formats and get the line numbers for free, TOML files are
+	// read into the same kind of nodes keeping their line numbers:
+	doc := &yaml.Node{}
+	if format == "toml" {
+		doc, err = tomlnode.Decode(file)
+	} else {
+		err = yaml.Unmarshal(file, doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if len(doc.Content) == 0 {
+		return []validationError{{Line: 1, Column: 1, Message: "the file is empty"}}, nil
+	}
+
+	return validateNode(schema, doc.Content[0], "", false), nil
+}
+
+// validateNode checks the node against the subset of JSON Schema
+// keywords that kparse.JSONSchema is able to generate.
+//
+// The values of secrets, and of everything inside them, are never
+// written on the error messages.
+func validateNode(schema *kparse.Schema, node *yaml.Node, path string, secret bool) (errs []validationError) {
+	secret = secret || schema.Secret || schema.WriteOnly
+
+	if node.Kind == yaml.AliasNode {
+		return validateNode(schema, node.Alias, path, secret)
+	}
+
+	report := func(n *yaml.Node, format string, args ...any) {
+		msg := fmt.Sprintf(format, args...)
+		if path != "" {
+			msg = path + ": " + msg
+		}
+		errs = append(errs, validationError{Line: n.Line, Column: n.Column, Message: msg})
+	}
+
+	if schema.Type != "" && !matchesType(schema.Type, node) {
+		report(node, "expected %s but got %s", schema.Type, describeNode(node, secret))
+		return errs
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		found := map[string]bool{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			found[key] = true
+
+			property := schema.Properties[key]
+			if property == nil {
+				property = schema.AdditionalProperties
+			}
+			if property != nil {
+				errs = append(errs, validateNode(property, value, joinPath(path, key), secret)...)
+			}
+		}
+
+		for _, key := range schema.Required {
+			if !found[key] {
+				report(node, "missing required key '%s'", key)
+			}
+		}
+
+		numProperties := len(node.Content) / 2
+		if schema.MinProperties != nil && numProperties < *schema.MinProperties {
+			report(node, "expected at least %d keys but got %d", *schema.MinProperties, numProperties)
+		}
+		if schema.MaxProperties != nil && numProperties > *schema.MaxProperties {
+			report(node, "expected at most %d keys but got %d", *schema.MaxProperties, numProperties)
+		}
+
+	case yaml.SequenceNode:
+		if schema.Items != nil {
+			for i, item := range node.Content {
+				errs = append(errs, validateNode(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), secret)...)
+			}
+		}
+
+		if schema.MinItems != nil && len(node.Content) < *schema.MinItems {
+			report(node, "expected at least %d items but got %d", *schema.MinItems, len(node.Content))
+		}
+		if schema.MaxItems != nil && len(node.Content) > *schema.MaxItems {
+			report(node, "expected at most %d items but got %d", *schema.MaxItems, len(node.Content))
+		}
+
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!str" {
+			length := utf8.RuneCountInString(node.Value)
+			if schema.MinLength != nil && length < *schema.MinLength {
+				report(node, "expected a length of at least %d but got %d", *schema.MinLength, length)
+			}
+			if schema.MaxLength != nil && length > *schema.MaxLength {
+				report(node, "expected a length of at most %d but got %d", *schema.MaxLength, length)
+			}
+			break
+		}
+
+		value, isNumber := nodeNumber(node)
+		if !isNumber {
+			break
+		}
+
+		check := func(limit json.Number, op string, isValid func(value, limit float64) bool) {
+			if limit == "" {
+				return
+			}
+			l, err := limit.Float64()
+			if err != nil || isValid(value, l) {
+				return
+			}
+
+			if secret {
+				report(node, "expected a value %s %s", op, limit)
+				return
+			}
+			report(node, "expected a value %s %s but got %s", op, limit, node.Value)
+		}
+		check(schema.Const, "=", func(v, l float64) bool { return v == l })
+		check(schema.Minimum, ">=", func(v, l float64) bool { return v >= l })
+		check(schema.Maximum, "<=", func(v, l float64) bool { return v <= l })
+		check(schema.ExclusiveMinimum, ">", func(v, l float64) bool { return v > l })
+		check(schema.ExclusiveMaximum, "<", func(v, l float64) bool { return v < l })
+	}
+
+	return errs
+}
+
+func matchesType(schemaType string, node *yaml.Node) bool {
+	switch schemaType {
+	case "object":
+		return node.Kind == yaml.MappingNode
+	case "array":
+		return node.Kind == yaml.SequenceNode
+	case "string":
+		// Dates are also accepted since they are read as strings:
+		return node.Kind == yaml.ScalarNode && (node.ShortTag() == "!!str" || node.ShortTag() == "!!timestamp")
+	case "boolean":
+		return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!bool"
+	case "integer":
+		value, isNumber := nodeNumber(node)
+		return isNumber && value == math.Trunc(value)
+	case "number":
+		_, isNumber := nodeNumber(node)
+		return isNumber
+	}
+
+	return true
+}
+
+func nodeNumber(node *yaml.Node) (float64, bool) {
+	if node.Kind != yaml.ScalarNode {
+		return 0, false
+	}
+
+	tag := node.ShortTag()
+	if tag != "!!int" && tag != "!!float" {
+		return 0, false
+	}
+
+	var value float64
+	err := node.Decode(&value)
+	if err != nil {
+		// Very large integers might not fit on a float64:
+		value, err = strconv.ParseFloat(node.Value, 64)
+	}
+	return value, err == nil
+}
+
+func describeNode(node *yaml.Node, secret bool) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "an object"
+	case yaml.SequenceNode:
+		return "an array"
+	}
+
+	if secret {
+		switch node.ShortTag() {
+		case "!!str":
+			return "a string"
+		case "!!null":
+			return "null"
+		case "!!int", "!!float":
+			return "a number"
+		case "!!bool":
+			return "a boolean"
+		}
+		return "a redacted value"
+	}
+
+	switch node.ShortTag() {
+	case "!!str":
+		return fmt.Sprintf("the string %q", node.Value)
+	case "!!null":
+		return "null"
+	}
+
+	return node.Value
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
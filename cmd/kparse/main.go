@@ -0,0 +1,17 @@
+// Command kparse validates and formats config files from the command line.
+//
+// Since this binary has no config types registered, the `schema` subcommand
+// is only useful on programs that register their own types with cli.Register
+// before calling cli.Main, e.g.:
+//
+//	func main() {
+//		cli.Register("server", &server.Config{})
+//		cli.Main()
+//	}
+package main
+
+import "github.com/teamcollab-net/kparse/cli"
+
+func main() {
+	cli.Main()
+}
@@ -0,0 +1,97 @@
+package kparse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WithDirPattern sets which files are loaded by ParseDir, e.g. "*.yaml",
+// by default all files with the .yaml, .yml or .json extensions are loaded.
+func WithDirPattern(pattern string) Option {
+	return func(opts *parseOptions) {
+		opts.dirPattern = pattern
+	}
+}
+
+// ParseDir parses all config files of a directory, e.g. conf.d directories
+// where each package adds its own file:
+//
+//	err := kparse.ParseDir("/etc/service/conf.d", &config)
+//
+// The files are merged in lexical order, so each file overrides the values
+// of the previous ones and nested maps are merged, and only then the default
+// values and validations are applied. Values that are overridden with different
+// values produce warnings naming both files, see WithWarnings, and values
+// that change from a map, list or single value into another are errors.
+// Lists are merged according to WithListMergeStrategy and the `merge` tags.
+//
+// Hidden files are ignored, and the keys are read from
+// the `yaml` tag unless WithTagName is used.
+func ParseDir(dir string, target any, opts ...Option) error {
+	return defaultParser.ParseDir(dir, target, opts...)
+}
+
+// ParseDir works like the top-level ParseDir using the settings of the Parser.
+func (p *Parser) ParseDir(dir string, target any, opts ...Option) error {
+	state := p.newParseState("yaml", opts)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	merger := newLayerMerger(state, target)
+	merger.reportConflicts = true
+
+	var merged LazyDecoder
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		match, err := state.matchesDirPattern(entry.Name())
+		if err != nil {
+			return err
+		}
+		if !match {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		value, err := FileSource(path).Load()
+		if err != nil {
+			return fmt.Errorf("error loading '%s': %w", path, err)
+		}
+
+		if state.options.includes {
+			value, err = state.resolveIncludes(value, path, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		merged, err = merger.mergeLayer(merged, withSource(path, value), path)
+		if err != nil {
+			return err
+		}
+	}
+
+	state.includesResolved = true
+	return state.parseRoot(target, merged)
+}
+
+func (s *parseState) matchesDirPattern(name string) (bool, error) {
+	if s.options.dirPattern == "" {
+		_, err := formatFromPath(name)
+		return err == nil, nil
+	}
+
+	match, err := filepath.Match(s.options.dirPattern, name)
+	if err != nil {
+		return false, fmt.Errorf("invalid dir pattern '%s': %w", s.options.dirPattern, err)
+	}
+
+	return match, nil
+}
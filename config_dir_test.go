@@ -0,0 +1,205 @@
+package kparse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestParseDir(t *testing.T) {
+	type Route struct {
+		Name    string `yaml:"name" validate:"required"`
+		Path    string `yaml:"path"`
+		Timeout int    `yaml:"timeout" default:"30"`
+	}
+
+	type Config struct {
+		MaxRetries int      `yaml:"maxRetries" default:"3" validate:"<=10"`
+		Domains    []string `yaml:"domains"`
+		Database   struct {
+			Host string `yaml:"host" validate:"required"`
+			Port int    `yaml:"port" default:"5432"`
+		} `yaml:"database"`
+		Routes []Route `yaml:"routes"`
+	}
+
+	files := map[string]string{
+		"10-base.yaml": `
+domains: [example.com]
+database:
+  host: localhost
+routes:
+  - name: users
+    path: /users
+`,
+		"20-prod.json": `{
+	"domains": ["www.example.com"],
+	"database": {"port": 5433},
+	"routes": [{"name": "users", "timeout": 60}, {"name": "orders", "path": "/orders"}]
+}`,
+		"30-empty.yaml": "",
+		".hidden.yaml":  "maxRetries: 100\n",
+		"README.md":     "not a config file",
+	}
+
+	tests := []struct {
+		desc               string
+		files              map[string]string
+		opts               []Option
+		expectedDomains    []string
+		expectedRoutes     []Route
+		expectedWarnings   []string
+		expectErrToContain []string
+	}{
+		{
+			desc:            "should replace lists by default",
+			files:           files,
+			expectedDomains: []string{"www.example.com"},
+			expectedRoutes: []Route{
+				{Name: "users", Timeout: 60},
+				{Name: "orders", Path: "/orders", Timeout: 30},
+			},
+			expectedWarnings: []string{
+				"domains: value from '10-base.yaml' is overridden by '20-prod.json'",
+				"routes: value from '10-base.yaml' is overridden by '20-prod.json'",
+			},
+		},
+		{
+			desc:            "should append lists",
+			files:           files,
+			opts:            []Option{WithListMergeStrategy(AppendLists)},
+			expectedDomains: []string{"example.com", "www.example.com"},
+			expectedRoutes: []Route{
+				{Name: "users", Path: "/users", Timeout: 30},
+				{Name: "users", Timeout: 60},
+				{Name: "orders", Path: "/orders", Timeout: 30},
+			},
+		},
+		{
+			desc:            "should merge lists by key",
+			files:           files,
+			opts:            []Option{WithListMergeStrategy(MergeListsByKey("name"))},
+			expectedDomains: []string{"example.com", "www.example.com"},
+			expectedRoutes: []Route{
+				{Name: "users", Path: "/users", Timeout: 60},
+				{Name: "orders", Path: "/orders", Timeout: 30},
+			},
+		},
+		{
+			desc:            "should only load the files matching the pattern",
+			files:           files,
+			opts:            []Option{WithDirPattern("*.yaml")},
+			expectedDomains: []string{"example.com"},
+			expectedRoutes:  []Route{{Name: "users", Path: "/users", Timeout: 30}},
+		},
+		{
+			desc: "should report the files of conflicting values",
+			files: map[string]string{
+				"a.yaml": "maxRetries: 5\n",
+				"b.yaml": "maxRetries: 5\n",
+				"c.yaml": "maxRetries: 7\n",
+				"d.yaml": "database:\n  host: localhost\n",
+				"e.yaml": "database: [localhost]\n",
+			},
+			expectErrToContain: []string{
+				"conflicting values for key 'database'",
+				"a map on '", "d.yaml'",
+				"a list on '", "e.yaml'",
+			},
+		},
+		{
+			desc: "should validate the merged values once",
+			files: map[string]string{
+				"a.yaml": "maxRetries: 50\n",
+				"b.yaml": "maxRetries: 5\ndatabase:\n  host: localhost\n",
+			},
+			expectedWarnings: []string{"maxRetries: value from 'a.yaml' is overridden by 'b.yaml'"},
+		},
+		{
+			desc: "should report list items without the merge key",
+			files: map[string]string{
+				"a.yaml": "routes: [{name: users}]\n",
+				"b.yaml": "routes: [{path: /users}]\n",
+			},
+			opts:               []Option{WithListMergeStrategy(MergeListsByKey("name"))},
+			expectErrToContain: []string{"error merging item 0 of 'routes' on '", "b.yaml'", "expected a map with the 'name' key"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range test.files {
+				err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+				tt.AssertNoErr(t, err)
+			}
+
+			var warnings []Warning
+			opts := append([]Option{WithWarnings(&warnings)}, test.opts...)
+
+			var config Config
+			err := ParseDir(dir, &config, opts...)
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, config.Domains, test.expectedDomains)
+			tt.AssertEqual(t, config.Routes, test.expectedRoutes)
+
+			var warningStrs []string
+			for _, warning := range warnings {
+				// Only the file names are checked:
+				warningStrs = append(warningStrs, strings.ReplaceAll(warning.String(), dir+string(filepath.Separator), ""))
+			}
+			tt.AssertEqual(t, warningStrs, test.expectedWarnings)
+		})
+	}
+
+	t.Run("should record the file of each value", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, content := range files {
+			err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+			tt.AssertNoErr(t, err)
+		}
+
+		var config Config
+		var origins Origins
+		err := ParseDir(dir, &config, WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, origins["database.host"], Origin{Source: filepath.Join(dir, "10-base.yaml"), Line: 4, Column: 9})
+		tt.AssertEqual(t, origins["database.port"].Source, filepath.Join(dir, "20-prod.json"))
+		tt.AssertEqual(t, origins["maxRetries"], Origin{Default: true})
+	})
+
+	t.Run("should keep the precision of json numbers", func(t *testing.T) {
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "10-base.yaml"), []byte("limits: {a: 1}\n"), 0o644)
+		tt.AssertNoErr(t, err)
+		err = os.WriteFile(filepath.Join(dir, "20-prod.json"), []byte(`{
+	"limits": {"b": 9007199254740993}
+}`), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config struct {
+			Limits map[string]int64 `yaml:"limits"`
+		}
+		err = ParseDir(dir, &config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.Limits, map[string]int64{"a": 1, "b": 9007199254740993})
+
+		err = os.WriteFile(filepath.Join(dir, "20-prod.json"), []byte(`{"limits": {"b": 2.7}}`), 0o644)
+		tt.AssertNoErr(t, err)
+		err = ParseDir(dir, &config)
+		tt.AssertErrContains(t, err, "2.7")
+	})
+
+	t.Run("should report missing directories", func(t *testing.T) {
+		var config Config
+		err := ParseDir(filepath.Join(t.TempDir(), "missing"), &config)
+		tt.AssertErrContains(t, err, "no such file or directory")
+	})
+}
@@ -0,0 +1,312 @@
+package kparse
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"reflect"
+	"strings"
+
+	"github.com/vingarcia/structi"
+)
+
+// GenerateDocs renders a reference documentation of the struct pointed
+// by structPtr, with one table for each level of nested structs.
+//
+// The keys are read from the `tagName` tag, the same way the Parse
+// functions do, and the output format can either be "markdown" or "html".
+//
+// Each row describes the full path of a key, its Go type, whether it is
+// required, its default value, its validation rules in plain English,
+// the environment variable on its `env` tag if it has one and the
+// description on its `desc` tag.
+func GenerateDocs(structPtr any, tagName string, format string) ([]byte, error) {
+	t := reflect.TypeOf(structPtr)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct pointer but got: %T", structPtr)
+	}
+
+	tables, err := docsTables(tagName, "", t.Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "markdown", "md":
+		return renderMarkdownDocs(tables), nil
+	case "html":
+		return renderHTMLDocs(tables)
+	}
+
+	return nil, fmt.Errorf("unsupported docs format: '%s', expected one of: markdown or html", format)
+}
+
+type docsTable struct {
+	Title  string
+	Rows   []docsRow
+	HasEnv bool
+}
+
+type docsRow struct {
+	Key         string
+	Type        string
+	Required    bool
+	Default     string
+	Rules       string
+	Env         string
+	Description string
+}
+
+// docsTables returns the table describing the struct t followed by
+// the tables of each of its nested structs, in the order they appear.
+func docsTables(tagName string, prefix string, t reflect.Type) ([]docsTable, error) {
+	info, err := structi.GetStructInfo(t)
+	if err != nil {
+		return nil, err
+	}
+
+	title := prefix
+	if title == "" {
+		title = "Root"
+	}
+
+	table := docsTable{Title: title}
+	var nestedTables []docsTable
+	for _, field := range info.Fields {
+		key := keyFromTag(field.Tags[tagName])
+		if key == "" {
+			continue
+		}
+
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		required, rules, err := describeValidations(field.Name, unwrapSecretType(field.Type), field.Tags["validate"])
+		if err != nil {
+			return nil, err
+		}
+
+		secret := isSecretField(field.Type, field.Tags)
+		defaultValue := field.Tags["default"]
+		if secret && defaultValue != "" {
+			defaultValue = redacted
+		}
+
+		table.Rows = append(table.Rows, docsRow{
+			Key:         path,
+			Type:        goTypeName(field.Type),
+			Required:    required,
+			Default:     defaultValue,
+			Rules:       rules,
+			Env:         field.Tags["env"],
+			Description: field.Tags["desc"],
+		})
+		if field.Tags["env"] != "" {
+			table.HasEnv = true
+		}
+
+		// The keys of maps are written as "*", e.g. "backends.*.url":
+		elemType := field.Type
+		for elemType.Kind() == reflect.Ptr || elemType.Kind() == reflect.Slice || elemType.Kind() == reflect.Array || elemType.Kind() == reflect.Map {
+			switch elemType.Kind() {
+			case reflect.Slice, reflect.Array:
+				path += "[]"
+			case reflect.Map:
+				path += ".*"
+			}
+			elemType = elemType.Elem()
+		}
+
+		if elemType.Kind() == reflect.Struct && !isSecretType(elemType) {
+			tables, err := docsTables(tagName, path, elemType)
+			if err != nil {
+				return nil, err
+			}
+
+			// The fields of secret structs are secrets too:
+			if secret {
+				redactDefaults(tables)
+			}
+			nestedTables = append(nestedTables, tables...)
+		}
+	}
+
+	return append([]docsTable{table}, nestedTables...), nil
+}
+
+// redactDefaults replaces the defaults on the tables by a redacted value.
+func redactDefaults(tables []docsTable) {
+	for i := range tables {
+		for j := range tables[i].Rows {
+			if tables[i].Rows[j].Default != "" {
+				tables[i].Rows[j].Default = redacted
+			}
+		}
+	}
+}
+
+// describeValidations translates the expressions of a `validate` tag
+// into plain English, e.g. ">0,<=10" becomes "must be > 0 and <= 10".
+//
+// Each expression is compiled with the same validator factories used by
+// the parser, so invalid expressions are reported here as well.
+func describeValidations(fieldName string, t reflect.Type, validateTag string) (required bool, rules string, _ error) {
+	if validateTag == "" {
+		return false, "", nil
+	}
+
+	var rangeRules, lenRules []string
+	for _, exp := range strings.Split(validateTag, ",") {
+		validatorName, rule := extractValidatorNameAndRule(exp)
+		if validatorName == "required" {
+			required = true
+			continue
+		}
+
+		factory, found := validatorFactoryMap[validatorFactoryMapKey{validatorName, t.Kind()}]
+		if !found {
+			return false, "", fmt.Errorf(
+				"unrecognized validation exp: '%s' on struct field: '%s'",
+				exp, fieldName,
+			)
+		}
+
+		_, err := factory(fieldName, rule)
+		if err != nil {
+			return false, "", err
+		}
+
+		op, limit := splitRule(rule)
+		switch validatorName {
+		case "":
+			rangeRules = append(rangeRules, op+" "+limit)
+		case "len":
+			lenRules = append(lenRules, op+" "+limit)
+		}
+	}
+
+	var sentences []string
+	if len(rangeRules) > 0 {
+		sentences = append(sentences, "must be "+strings.Join(rangeRules, " and "))
+	}
+	if len(lenRules) > 0 {
+		sentences = append(sentences, "length must be "+strings.Join(lenRules, " and "))
+	}
+
+	return required, strings.Join(sentences, "; "), nil
+}
+
+// goTypeName returns the Go type of a field, anonymous structs
+// are abbreviated as "struct" so they don't pollute the tables.
+func goTypeName(t reflect.Type) string {
+	if t.Name() != "" {
+		return t.String()
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + goTypeName(t.Elem())
+	case reflect.Slice:
+		return "[]" + goTypeName(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), goTypeName(t.Elem()))
+	case reflect.Map:
+		return "map[" + goTypeName(t.Key()) + "]" + goTypeName(t.Elem())
+	case reflect.Struct:
+		return "struct"
+	}
+
+	return t.String()
+}
+
+func renderMarkdownDocs(tables []docsTable) []byte {
+	var buf bytes.Buffer
+	for i, table := range tables {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+
+		fmt.Fprintf(&buf, "## %s\n\n", table.Title)
+
+		header := []string{"Key", "Type", "Required", "Default", "Validation"}
+		if table.HasEnv {
+			header = append(header, "Env Var")
+		}
+		header = append(header, "Description")
+
+		buf.WriteString("| " + strings.Join(header, " | ") + " |\n")
+		buf.WriteString(strings.Repeat("| --- ", len(header)) + "|\n")
+
+		for _, row := range table.Rows {
+			cells := []string{
+				markdownCode(row.Key),
+				markdownCode(row.Type),
+				yesOrNo(row.Required),
+				markdownCode(row.Default),
+				markdownEscape(row.Rules),
+			}
+			if table.HasEnv {
+				cells = append(cells, markdownCode(row.Env))
+			}
+			cells = append(cells, markdownEscape(row.Description))
+
+			buf.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + strings.ReplaceAll(s, "|", `\|`) + "`"
+}
+
+var markdownEscaper = strings.NewReplacer(
+	"|", `\|`,
+	"<", "&lt;",
+	">", "&gt;",
+	"\n", " ",
+)
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+func yesOrNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+var htmlDocsTemplate = template.Must(template.New("docs").Funcs(template.FuncMap{
+	"yesOrNo": yesOrNo,
+}).Parse(`{{- range $table := . -}}
+<h2>{{ .Title }}</h2>
+<table>
+  <thead>
+    <tr><th>Key</th><th>Type</th><th>Required</th><th>Default</th><th>Validation</th>{{ if .HasEnv }}<th>Env Var</th>{{ end }}<th>Description</th></tr>
+  </thead>
+  <tbody>
+{{- range .Rows }}
+    <tr><td><code>{{ .Key }}</code></td><td><code>{{ .Type }}</code></td><td>{{ yesOrNo .Required }}</td><td>{{ if .Default }}<code>{{ .Default }}</code>{{ end }}</td><td>{{ .Rules }}</td>{{ if $table.HasEnv }}<td>{{ if .Env }}<code>{{ .Env }}</code>{{ end }}</td>{{ end }}<td>{{ .Description }}</td></tr>
+{{- end }}
+  </tbody>
+</table>
+{{ end -}}
+`))
+
+func renderHTMLDocs(tables []docsTable) ([]byte, error) {
+	var buf bytes.Buffer
+	err := htmlDocsTemplate.Execute(&buf, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,142 @@
+package kparse
+
+import (
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestGenerateDocs(t *testing.T) {
+	type docsConfig struct {
+		SecretKey int    `yaml:"secretKey" validate:"required" desc:"Key used for signing tokens"`
+		BaseURL   string `yaml:"baseUrl" default:"https://example.com"`
+
+		Address struct {
+			Street string `yaml:"street" default:"defaultStreet"`
+		} `yaml:"address"`
+
+		MaxRetries int      `yaml:"maxRetries" validate:">0,<=10"`
+		Domains    []string `yaml:"domains" validate:"len>=1"`
+
+		Users []struct {
+			Name string `yaml:"name" validate:"required,len<10" env:"USER_NAME"`
+		} `yaml:"users"`
+
+		Cache int `yaml:"-"`
+	}
+
+	tests := []struct {
+		desc               string
+		format             string
+		structPtr          any
+		expectedOutput     string
+		expectErrToContain []string
+	}{
+		{
+			desc:      "should generate one markdown table per struct level",
+			format:    "markdown",
+			structPtr: &docsConfig{},
+			expectedOutput: "## Root\n" +
+				"\n" +
+				"| Key | Type | Required | Default | Validation | Description |\n" +
+				"| --- | --- | --- | --- | --- | --- |\n" +
+				"| `secretKey` | `int` | yes |  |  | Key used for signing tokens |\n" +
+				"| `baseUrl` | `string` | no | `https://example.com` |  |  |\n" +
+				"| `address` | `struct` | no |  |  |  |\n" +
+				"| `maxRetries` | `int` | no |  | must be &gt; 0 and &lt;= 10 |  |\n" +
+				"| `domains` | `[]string` | no |  | length must be &gt;= 1 |  |\n" +
+				"| `users` | `[]struct` | no |  |  |  |\n" +
+				"\n" +
+				"## address\n" +
+				"\n" +
+				"| Key | Type | Required | Default | Validation | Description |\n" +
+				"| --- | --- | --- | --- | --- | --- |\n" +
+				"| `address.street` | `string` | no | `defaultStreet` |  |  |\n" +
+				"\n" +
+				"## users[]\n" +
+				"\n" +
+				"| Key | Type | Required | Default | Validation | Env Var | Description |\n" +
+				"| --- | --- | --- | --- | --- | --- | --- |\n" +
+				"| `users[].name` | `string` | yes |  | length must be &lt; 10 | `USER_NAME` |  |\n",
+		},
+		{
+			desc:   "should generate html tables",
+			format: "html",
+			structPtr: &struct {
+				MaxRetries int `yaml:"maxRetries" validate:">0,<=10" desc:"Retries <per request>"`
+			}{},
+			expectedOutput: "<h2>Root</h2>\n" +
+				"<table>\n" +
+				"  <thead>\n" +
+				"    <tr><th>Key</th><th>Type</th><th>Required</th><th>Default</th><th>Validation</th><th>Description</th></tr>\n" +
+				"  </thead>\n" +
+				"  <tbody>\n" +
+				"    <tr><td><code>maxRetries</code></td><td><code>int</code></td><td>no</td><td></td><td>must be &gt; 0 and &lt;= 10</td><td>Retries &lt;per request&gt;</td></tr>\n" +
+				"  </tbody>\n" +
+				"</table>\n",
+		},
+		{
+			desc:   "should generate tables for structs used as map values",
+			format: "markdown",
+			structPtr: &struct {
+				Backends map[string]struct {
+					URL string `yaml:"url" validate:"required"`
+				} `yaml:"backends"`
+			}{},
+			expectedOutput: "## Root\n\n" +
+				"| Key | Type | Required | Default | Validation | Description |\n" +
+				"| --- | --- | --- | --- | --- | --- |\n" +
+				"| `backends` | `map[string]struct` | no |  |  |  |\n" +
+				"\n## backends.*\n\n" +
+				"| Key | Type | Required | Default | Validation | Description |\n" +
+				"| --- | --- | --- | --- | --- | --- |\n" +
+				"| `backends.*.url` | `string` | yes |  |  |  |\n",
+		},
+		{
+			desc:   "should redact the defaults of secrets",
+			format: "markdown",
+			structPtr: &struct {
+				Token string `yaml:"token" secret:"true" default:"hunter2"`
+				Auth  struct {
+					Pin int `yaml:"pin" default:"4242"`
+				} `yaml:"auth" secret:"true"`
+			}{},
+			expectedOutput: "## Root\n\n" +
+				"| Key | Type | Required | Default | Validation | Description |\n" +
+				"| --- | --- | --- | --- | --- | --- |\n" +
+				"| `token` | `string` | no | `******` |  |  |\n" +
+				"| `auth` | `struct` | no |  |  |  |\n" +
+				"\n## auth\n\n" +
+				"| Key | Type | Required | Default | Validation | Description |\n" +
+				"| --- | --- | --- | --- | --- | --- |\n" +
+				"| `auth.pin` | `int` | no | `******` |  |  |\n",
+		},
+		{
+			desc:   "should report invalid validation rules",
+			format: "markdown",
+			structPtr: &struct {
+				MaxRetries int `yaml:"maxRetries" validate:">foo"`
+			}{},
+			expectErrToContain: []string{"range validator", "foo"},
+		},
+		{
+			desc:               "should report unsupported formats",
+			format:             "pdf",
+			structPtr:          &docsConfig{},
+			expectErrToContain: []string{"unsupported", "pdf"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			output, err := GenerateDocs(test.structPtr, "yaml", test.format)
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, string(output), test.expectedOutput)
+		})
+	}
+}
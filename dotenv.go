@@ -0,0 +1,383 @@
+package kparse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/vingarcia/structi"
+)
+
+// WithSetenv makes the dotenv parsers also set the variables of the file on
+// the process environment, keeping the variables that were already set, so
+// the real environment variables override the values of the file.
+func WithSetenv(enabled bool) Option {
+	return func(opts *parseOptions) {
+		opts.setenv = enabled
+	}
+}
+
+func MustParseDotenvFile(path string, targetStruct any, opts ...Option) {
+	err := ParseDotenvFile(path, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ParseDotenvFile parses a .env file into the target struct, see ParseDotenv.
+func ParseDotenvFile(path string, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseDotenvFile(path, targetStruct, opts...)
+}
+
+func MustParseDotenv(file []byte, targetStruct any, opts ...Option) {
+	err := ParseDotenv(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ParseDotenv parses the contents of a .env file into the target struct,
+// where each variable is read into the field with its name on the `env` tag:
+//
+//	var config struct {
+//		DatabaseURL string   `env:"DATABASE_URL" validate:"required"`
+//		Domains     []string `env:"DOMAINS"` // comma separated
+//		Redis       struct {
+//			Host string `env:"REDIS_HOST" default:"localhost"`
+//		}
+//	}
+//
+// Nested structs read their fields from the same variables, and fields
+// without the `env` tag are matched by their Go names unless a naming
+// strategy is set. Values can be quoted with single quotes, which are
+// read literally, or double quotes, which accept escapes such as `\n`,
+// and both can span multiple lines:
+//
+//	# comments are ignored
+//	export DATABASE_URL=postgres://localhost/app # so is the export prefix
+//	TLS_CERT="-----BEGIN CERTIFICATE-----
+//	...
+//	-----END CERTIFICATE-----"
+func ParseDotenv(file []byte, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseDotenv(file, targetStruct, opts...)
+}
+
+func MustParseDotenvFromReader(file io.Reader, targetStruct any, opts ...Option) {
+	err := ParseDotenvFromReader(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func ParseDotenvFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseDotenvFromReader(file, targetStruct, opts...)
+}
+
+// ParseDotenvFile works like the top-level ParseDotenvFile using the settings of the Parser.
+func (p *Parser) ParseDotenvFile(path string, targetStruct any, opts ...Option) (err error) {
+	// The path is used as the source name unless the caller set another one:
+	opts = append([]Option{WithSourceName(path)}, opts...)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, file.Close())
+	}()
+
+	return p.ParseDotenvFromReader(file, targetStruct, opts...)
+}
+
+// ParseDotenv works like the top-level ParseDotenv using the settings of the Parser.
+func (p *Parser) ParseDotenv(file []byte, targetStruct any, opts ...Option) error {
+	return p.ParseDotenvFromReader(bytes.NewReader(file), targetStruct, opts...)
+}
+
+// ParseDotenvFromReader works like the top-level ParseDotenvFromReader using the settings of the Parser.
+func (p *Parser) ParseDotenvFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	t := reflect.TypeOf(targetStruct)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected struct pointer but got: %T", targetStruct)
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	vars, err := parseDotenvVars(string(content))
+	if err != nil {
+		return err
+	}
+
+	state := p.newParseState("env", opts)
+	if state.options.namingStrategy == nil {
+		// So nested structs can be found without `env` tags:
+		state.options.namingStrategy = func(fieldName string) string {
+			return fieldName
+		}
+	}
+
+	values := map[string]dotenvVar{}
+	for _, v := range vars {
+		values[v.name] = v
+	}
+
+	if state.options.setenv {
+		for _, v := range values {
+			if _, found := os.LookupEnv(v.name); found {
+				continue
+			}
+
+			err := os.Setenv(v.name, v.value)
+			if err != nil {
+				return fmt.Errorf("error setting env var '%s': %w", v.name, err)
+			}
+		}
+	}
+
+	usedVars := map[string]bool{}
+	tree, err := state.envTree(t.Elem(), values, usedVars)
+	if err != nil {
+		return err
+	}
+
+	// Kept so strict mode reports the unknown variables:
+	for name, v := range values {
+		if _, found := tree[name]; !found && !usedVars[name] {
+			tree[name] = v.decoder()
+		}
+	}
+
+	return state.parseRoot(targetStruct, mapDecoder(tree))
+}
+
+// envTree arranges the variables into nested maps following the
+// nested structs of t, so they can be parsed like any other map.
+func (s *parseState) envTree(t reflect.Type, values map[string]dotenvVar, usedVars map[string]bool) (map[string]LazyDecoder, error) {
+	info, err := structi.GetStructInfo(t)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := map[string]LazyDecoder{}
+	for _, field := range info.Fields {
+		key := s.tagKey(field.Name, field.Tags)
+		if key == "" {
+			continue
+		}
+
+		_, hooked := s.options.decodeHooks[field.Type]
+		if field.Kind == reflect.Struct && !isSecretType(field.Type) && !hooked {
+			nested, err := s.envTree(field.Type, values, usedVars)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(nested) > 0 {
+				tree[key] = mapDecoder(nested)
+			}
+			continue
+		}
+
+		names := []string{key}
+		if field.Tags["aliases"] != "" {
+			for _, alias := range strings.Split(field.Tags["aliases"], ",") {
+				names = append(names, strings.TrimSpace(alias))
+			}
+		}
+
+		for _, name := range names {
+			v, found := s.lookupEnvVar(values, name)
+			if !found {
+				continue
+			}
+			usedVars[name] = true
+
+			isList := field.Kind == reflect.Slice || field.Kind == reflect.Array
+			if !isList || hooked {
+				tree[name] = v.decoder()
+				continue
+			}
+
+			// Lists are written as comma separated values:
+			var items []LazyDecoder
+			if strings.TrimSpace(v.value) != "" {
+				for _, item := range strings.Split(v.value, ",") {
+					items = append(items, dotenvVar{
+						name:   v.name,
+						value:  strings.TrimSpace(item),
+						line:   v.line,
+						column: v.column,
+					}.decoder())
+				}
+			}
+			tree[name] = sliceDecoder(items)
+		}
+	}
+
+	return tree, nil
+}
+
+// lookupEnvVar returns the variable of the file, or with the WithSetenv
+// option the variable of the environment that overrides the file.
+func (s *parseState) lookupEnvVar(values map[string]dotenvVar, name string) (dotenvVar, bool) {
+	v, found := values[name]
+	if !s.options.setenv {
+		return v, found
+	}
+
+	envValue, envFound := os.LookupEnv(name)
+	if !envFound || (found && envValue == v.value) {
+		return v, found
+	}
+
+	return dotenvVar{name: name, value: envValue}, true
+}
+
+// dotenvVar is a variable read from a .env file,
+// or from the environment if its line is zero.
+type dotenvVar struct {
+	name   string
+	value  string
+	line   int
+	column int
+}
+
+func (v dotenvVar) decoder() LazyDecoder {
+	scalar := scalarDecoder(v.value)
+	return func(target any) error {
+		if probe, ok := target.(*originProbe); ok {
+			probe.line = v.line
+			probe.column = v.column
+			probe.envVar = v.name
+			return nil
+		}
+
+		return scalar(target)
+	}
+}
+
+var dotenvNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// parseDotenvVars reads the variables of a .env file in the order they are declared.
+func parseDotenvVars(content string) ([]dotenvVar, error) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var vars []dotenvVar
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := lines[i]
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq == -1 {
+			return nil, fmt.Errorf("line %d: expected 'NAME=value' but got '%s'", lineNumber, trimmed)
+		}
+
+		name := strings.TrimSpace(line[:eq])
+		if exported, found := strings.CutPrefix(name, "export"); found && strings.TrimSpace(exported) != exported {
+			name = strings.TrimSpace(exported)
+		}
+		if !dotenvNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("line %d: invalid variable name '%s'", lineNumber, name)
+		}
+
+		start := eq + 1
+		for start < len(line) && (line[start] == ' ' || line[start] == '\t') {
+			start++
+		}
+
+		v := dotenvVar{name: name, line: lineNumber, column: start + 1}
+		rest := line[start:]
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			// Unquoted values end at the comments:
+			if index := strings.Index(rest, " #"); index != -1 {
+				rest = rest[:index]
+			}
+			if index := strings.Index(rest, "\t#"); index != -1 {
+				rest = rest[:index]
+			}
+			v.value = strings.TrimSpace(rest)
+			vars = append(vars, v)
+			continue
+		}
+
+		// Quoted values can span multiple lines:
+		for {
+			value, remainder, closed := readQuotedValue(rest)
+			if closed {
+				remainder = strings.TrimSpace(remainder)
+				if remainder != "" && !strings.HasPrefix(remainder, "#") {
+					return nil, fmt.Errorf("line %d: unexpected characters after the closing quote: '%s'", i+1, remainder)
+				}
+
+				v.value = value
+				break
+			}
+
+			if i+1 >= len(lines) {
+				return nil, fmt.Errorf("line %d: missing closing quote on the value of '%s'", lineNumber, name)
+			}
+			i++
+			rest += "\n" + lines[i]
+		}
+		vars = append(vars, v)
+	}
+
+	return vars, nil
+}
+
+// readQuotedValue reads a value starting with a single or double quote,
+// returning what comes after the closing quote, if it was found.
+func readQuotedValue(text string) (value string, remainder string, closed bool) {
+	quote := text[0]
+	if quote == '\'' {
+		end := strings.IndexByte(text[1:], '\'')
+		if end == -1 {
+			return "", "", false
+		}
+		return text[1 : end+1], text[end+2:], true
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '"':
+			return b.String(), text[i+1:], true
+
+		case c == '\\' && i+1 < len(text):
+			i++
+			switch text[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$', '\'':
+				b.WriteByte(text[i])
+			default:
+				// Unknown escapes are kept as they are:
+				b.WriteByte('\\')
+				b.WriteByte(text[i])
+			}
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", "", false
+}
@@ -0,0 +1,143 @@
+package kparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestParseDotenv(t *testing.T) {
+	type Config struct {
+		DatabaseURL string   `env:"DATABASE_URL" validate:"required"`
+		MaxRetries  int      `env:"MAX_RETRIES" default:"3"`
+		Debug       bool     `env:"DEBUG"`
+		Domains     []string `env:"DOMAINS"`
+		Greeting    string   `env:"GREETING"`
+		Literal     string   `env:"LITERAL"`
+		Cert        string   `env:"CERT"`
+		Redis       struct {
+			Host string `env:"REDIS_HOST" default:"localhost"`
+			Port int    `env:"REDIS_PORT" default:"6379"`
+		}
+	}
+
+	tests := []struct {
+		desc               string
+		file               string
+		opts               []Option
+		expectedConfig     Config
+		expectErrToContain []string
+	}{
+		{
+			desc: "should parse quoting, escapes, export prefixes, comments and multiline values",
+			file: `# Local settings
+export DATABASE_URL=postgres://localhost/app # inline comment
+MAX_RETRIES = 5
+DEBUG=true
+DOMAINS=a.com, b.com
+GREETING="hello\tworld\n\"quoted\" # not a comment"
+LITERAL='no \n escapes # here'
+CERT="-----BEGIN-----
+line
+-----END-----"
+
+REDIS_PORT=6380
+`,
+			expectedConfig: Config{
+				DatabaseURL: "postgres://localhost/app",
+				MaxRetries:  5,
+				Debug:       true,
+				Domains:     []string{"a.com", "b.com"},
+				Greeting:    "hello\tworld\n\"quoted\" # not a comment",
+				Literal:     `no \n escapes # here`,
+				Cert:        "-----BEGIN-----\nline\n-----END-----",
+				Redis: struct {
+					Host string `env:"REDIS_HOST" default:"localhost"`
+					Port int    `env:"REDIS_PORT" default:"6379"`
+				}{Host: "localhost", Port: 6380},
+			},
+		},
+		{
+			desc:               "should validate the values",
+			file:               "MAX_RETRIES=5\n",
+			expectErrToContain: []string{"missing required field 'DATABASE_URL'"},
+		},
+		{
+			desc:               "should report unknown variables in strict mode",
+			file:               "DATABASE_URL=postgres://localhost/app\nUNUSED=1\n",
+			opts:               []Option{WithStrict(true)},
+			expectErrToContain: []string{"unknown keys: 'UNUSED'"},
+		},
+		{
+			desc:               "should report lines without values",
+			file:               "DATABASE_URL=postgres://localhost/app\nDEBUG\n",
+			expectErrToContain: []string{"line 2: expected 'NAME=value' but got 'DEBUG'"},
+		},
+		{
+			desc:               "should report invalid names",
+			file:               "DATABASE URL=postgres://localhost/app\n",
+			expectErrToContain: []string{"line 1: invalid variable name 'DATABASE URL'"},
+		},
+		{
+			desc:               "should report unclosed quotes",
+			file:               "DATABASE_URL=postgres://localhost/app\nCERT=\"-----BEGIN-----\nline\n",
+			expectErrToContain: []string{"line 2: missing closing quote on the value of 'CERT'"},
+		},
+		{
+			desc:               "should report characters after closing quotes",
+			file:               "DATABASE_URL='postgres://localhost/app' extra\n",
+			expectErrToContain: []string{"line 1: unexpected characters after the closing quote: 'extra'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var config Config
+			err := ParseDotenv([]byte(test.file), &config, test.opts...)
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, config, test.expectedConfig)
+		})
+	}
+
+	t.Run("should record the origins of the variables", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		err := os.WriteFile(path, []byte("DATABASE_URL=postgres://localhost/app\nREDIS_HOST = redis\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config Config
+		var origins Origins
+		err = ParseDotenvFile(path, &config, WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, origins["DATABASE_URL"], Origin{Source: path, Line: 1, Column: 14, EnvVar: "DATABASE_URL"})
+		tt.AssertEqual(t, origins["Redis.REDIS_HOST"], Origin{Source: path, Line: 2, Column: 14, EnvVar: "REDIS_HOST"})
+		tt.AssertEqual(t, origins["Redis.REDIS_PORT"], Origin{Default: true})
+	})
+
+	t.Run("should layer the file with the process environment", func(t *testing.T) {
+		t.Setenv("KPARSE_TEST_HOST", "from-env")
+		t.Setenv("KPARSE_TEST_PORT", "")
+		os.Unsetenv("KPARSE_TEST_PORT")
+		t.Cleanup(func() { os.Unsetenv("KPARSE_TEST_PORT") })
+
+		var config struct {
+			Host string `env:"KPARSE_TEST_HOST"`
+			Port int    `env:"KPARSE_TEST_PORT"`
+		}
+		var origins Origins
+		file := []byte("KPARSE_TEST_HOST=from-file\nKPARSE_TEST_PORT=8080\n")
+		err := ParseDotenv(file, &config, WithSetenv(true), WithSourceName(".env"), WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.Host, "from-env")
+		tt.AssertEqual(t, config.Port, 8080)
+		tt.AssertEqual(t, os.Getenv("KPARSE_TEST_HOST"), "from-env")
+		tt.AssertEqual(t, os.Getenv("KPARSE_TEST_PORT"), "8080")
+		tt.AssertEqual(t, origins["KPARSE_TEST_HOST"].String(), "env KPARSE_TEST_HOST")
+		tt.AssertEqual(t, origins["KPARSE_TEST_PORT"].String(), ".env:2:18 (env KPARSE_TEST_PORT)")
+	})
+}
@@ -0,0 +1,219 @@
+package kparse
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The names of the environment variables used for loading the
+// decryption key when none was set with SetDecryptionKey:
+const (
+	DecryptionKeyEnvVar     = "KPARSE_DECRYPTION_KEY"
+	DecryptionKeyFileEnvVar = "KPARSE_DECRYPTION_KEY_FILE"
+)
+
+var decryptionKey struct {
+	mu  sync.RWMutex
+	key []byte
+}
+
+// SetDecryptionKey sets the key used for decrypting values in the
+// `ENC[...]` format, the key must have 16, 24 or 32 bytes for using
+// AES-128, AES-192 or AES-256 respectively.
+//
+// If no key is set, kparse will try to load it from the
+// KPARSE_DECRYPTION_KEY or KPARSE_DECRYPTION_KEY_FILE env vars
+// the first time an encrypted value is found.
+func SetDecryptionKey(key []byte) error {
+	_, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+
+	decryptionKey.mu.Lock()
+	defer decryptionKey.mu.Unlock()
+	decryptionKey.key = key
+
+	return nil
+}
+
+// LoadDecryptionKeyFile sets the decryption key from a file
+// containing a key encoded in base64, as generated by GenerateKey.
+func LoadDecryptionKeyFile(path string) error {
+	key, err := ReadKeyFile(path)
+	if err != nil {
+		return err
+	}
+
+	return SetDecryptionKey(key)
+}
+
+// LoadDecryptionKeyFromEnv sets the decryption key from an environment
+// variable containing a key encoded in base64, as generated by GenerateKey.
+func LoadDecryptionKeyFromEnv(name string) error {
+	encodedKey, found := os.LookupEnv(name)
+	if !found {
+		return fmt.Errorf("environment variable '%s' is not set", name)
+	}
+
+	key, err := DecodeKey(encodedKey)
+	if err != nil {
+		return fmt.Errorf("invalid key on environment variable '%s': %w", name, err)
+	}
+
+	return SetDecryptionKey(key)
+}
+
+// GenerateKey generates a new random AES-256 key encoded in base64.
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// DecodeKey decodes a key encoded in base64, as generated by GenerateKey.
+func DecodeKey(encodedKey string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil {
+		return nil, fmt.Errorf("key is not valid base64: %w", err)
+	}
+
+	_, err = aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// ReadKeyFile reads a file containing a key encoded in base64.
+func ReadKeyFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := DecodeKey(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key on file '%s': %w", path, err)
+	}
+
+	return key, nil
+}
+
+// Encrypt encrypts the plaintext with AES-GCM, returning it in
+// the `ENC[...]` format, ready for being written on config files.
+func Encrypt(key []byte, plaintext string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return "ENC[" + base64.StdEncoding.EncodeToString(ciphertext) + "]", nil
+}
+
+// Decrypt decrypts a value in the `ENC[...]` format generated by Encrypt.
+func Decrypt(key []byte, value string) (string, error) {
+	encoded, ok := encryptedPayload(value)
+	if !ok {
+		return "", fmt.Errorf("expected value in the ENC[...] format")
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("encrypted value is not valid base64: %w", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return "", fmt.Errorf("encrypted value is too short")
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt value, is it the right key? %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func encryptedPayload(value string) (string, bool) {
+	if !strings.HasPrefix(value, "ENC[") || !strings.HasSuffix(value, "]") {
+		return "", false
+	}
+
+	return value[len("ENC[") : len(value)-1], true
+}
+
+// decryptValue decrypts a value found on a config file using the
+// key set with SetDecryptionKey or the one on the default env vars.
+func decryptValue(value string) (string, error) {
+	decryptionKey.mu.RLock()
+	key := decryptionKey.key
+	decryptionKey.mu.RUnlock()
+
+	if key == nil {
+		var err error
+		key, err = loadDefaultDecryptionKey()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return Decrypt(key, value)
+}
+
+func loadDefaultDecryptionKey() ([]byte, error) {
+	if _, found := os.LookupEnv(DecryptionKeyEnvVar); found {
+		err := LoadDecryptionKeyFromEnv(DecryptionKeyEnvVar)
+		if err != nil {
+			return nil, err
+		}
+	} else if path, found := os.LookupEnv(DecryptionKeyFileEnvVar); found {
+		err := LoadDecryptionKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, errors.New(
+			"found encrypted value but no decryption key was set, use kparse.SetDecryptionKey() or the " +
+				DecryptionKeyEnvVar + " or " + DecryptionKeyFileEnvVar + " env vars",
+		)
+	}
+
+	decryptionKey.mu.RLock()
+	defer decryptionKey.mu.RUnlock()
+	return decryptionKey.key, nil
+}
@@ -0,0 +1,120 @@
+package kparse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestEncryption(t *testing.T) {
+	encodedKey, err := GenerateKey()
+	tt.AssertNoErr(t, err)
+	key, err := DecodeKey(encodedKey)
+	tt.AssertNoErr(t, err)
+
+	t.Run("should decrypt encrypted values", func(t *testing.T) {
+		encrypted, err := Encrypt(key, "fakePassword")
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, strings.HasPrefix(encrypted, "ENC["), true)
+
+		plaintext, err := Decrypt(key, encrypted)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, plaintext, "fakePassword")
+	})
+
+	t.Run("should fail to decrypt with the wrong key", func(t *testing.T) {
+		encrypted, err := Encrypt(key, "fakePassword")
+		tt.AssertNoErr(t, err)
+
+		otherKey, err := GenerateKey()
+		tt.AssertNoErr(t, err)
+		decodedOtherKey, err := DecodeKey(otherKey)
+		tt.AssertNoErr(t, err)
+
+		_, err = Decrypt(decodedOtherKey, encrypted)
+		tt.AssertErrContains(t, err, "unable to decrypt")
+	})
+
+	t.Run("should reject keys of invalid sizes", func(t *testing.T) {
+		err := SetDecryptionKey([]byte("tooShort"))
+		tt.AssertErrContains(t, err, "key size")
+	})
+
+	t.Run("should decrypt values while parsing", func(t *testing.T) {
+		t.Cleanup(resetDecryptionKey)
+
+		err := SetDecryptionKey(key)
+		tt.AssertNoErr(t, err)
+
+		encryptedPassword, err := Encrypt(key, "fakePassword")
+		tt.AssertNoErr(t, err)
+		encryptedPort, err := Encrypt(key, "5432")
+		tt.AssertNoErr(t, err)
+
+		var config struct {
+			Password Secret[string] `yaml:"password"`
+			Port     int            `yaml:"port" validate:"<1000"`
+			User     string         `yaml:"user"`
+		}
+		err = ParseYAML([]byte(`
+password: `+encryptedPassword+`
+port: `+encryptedPort+`
+user: fakeUser
+`), &config)
+
+		// Decrypted values are treated as secrets:
+		tt.AssertErrContains(t, err, "Port", "******", "<1000")
+		tt.AssertEqual(t, strings.Contains(err.Error(), "5432"), false, err.Error())
+
+		tt.AssertEqual(t, config.Password.Value(), "fakePassword")
+		tt.AssertEqual(t, config.Port, 5432)
+		tt.AssertEqual(t, config.User, "fakeUser")
+	})
+
+	t.Run("should load the key from the default env vars", func(t *testing.T) {
+		encrypted, err := Encrypt(key, "fakePassword")
+		tt.AssertNoErr(t, err)
+
+		var config struct {
+			Password string `json:"password"`
+		}
+
+		t.Run("using the key env var", func(t *testing.T) {
+			t.Cleanup(resetDecryptionKey)
+			t.Setenv(DecryptionKeyEnvVar, encodedKey)
+
+			err = ParseJSON([]byte(`{"password": "`+encrypted+`"}`), &config)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, config.Password, "fakePassword")
+		})
+
+		t.Run("using the key file env var", func(t *testing.T) {
+			t.Cleanup(resetDecryptionKey)
+
+			keyFile := filepath.Join(t.TempDir(), "key")
+			err := os.WriteFile(keyFile, []byte(encodedKey+"\n"), 0o600)
+			tt.AssertNoErr(t, err)
+			t.Setenv(DecryptionKeyFileEnvVar, keyFile)
+
+			err = ParseJSON([]byte(`{"password": "`+encrypted+`"}`), &config)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, config.Password, "fakePassword")
+		})
+
+		t.Run("without any key", func(t *testing.T) {
+			t.Cleanup(resetDecryptionKey)
+
+			err = ParseJSON([]byte(`{"password": "`+encrypted+`"}`), &config)
+			tt.AssertErrContains(t, err, "password", "no decryption key", DecryptionKeyEnvVar)
+		})
+	})
+}
+
+func resetDecryptionKey() {
+	decryptionKey.mu.Lock()
+	defer decryptionKey.mu.Unlock()
+	decryptionKey.key = nil
+}
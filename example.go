@@ -0,0 +1,220 @@
+package kparse
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/vingarcia/structi"
+	"gopkg.in/yaml.v3"
+)
+
+// GenerateExample renders an example config file for the struct pointed
+// by structPtr in the given format, which can be "yaml", "json" or "toml".
+//
+// The format name is also used as the tag name for reading the keys,
+// so a "yaml" example uses the `yaml` tags of the struct.
+//
+// Every key is rendered with its default value (or the zero value
+// of its type) and, on YAML and TOML, with a comment describing the
+// type of the field, whether it is required, its validation rules
+// and the contents of its `desc` tag.
+func GenerateExample(structPtr any, format string) ([]byte, error) {
+	t := reflect.TypeOf(structPtr)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct pointer but got: %T", structPtr)
+	}
+
+	node, err := exampleStructNode(format, t.Elem(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "yaml":
+		return encodeNodeAsYAML(node)
+	case "json":
+		return encodeNodeAsJSON(node)
+	case "toml":
+		return encodeNodeAsTOML(node)
+	}
+
+	return nil, fmt.Errorf("unsupported example format: '%s', expected one of: yaml, json or toml", format)
+}
+
+// exampleStructNode renders the fields of the struct, the secret argument
+// is set for the fields of secret structs, whose defaults are left out.
+func exampleStructNode(tagName string, t reflect.Type, secret bool) (*yaml.Node, error) {
+	info, err := structi.GetStructInfo(t)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, field := range info.Fields {
+		key := keyFromTag(field.Tags[tagName])
+		if key == "" {
+			continue
+		}
+
+		// The defaults of secrets are not published,
+		// so they are rendered as their zero values:
+		fieldSecret := secret || isSecretField(field.Type, field.Tags)
+		defaultYAML := field.Tags["default"]
+		if fieldSecret {
+			defaultYAML = ""
+		}
+
+		value, err := exampleValueNode(tagName, field.Type, defaultYAML, fieldSecret)
+		if err != nil {
+			return nil, fmt.Errorf("error generating example for field '%s': %w", field.Name, err)
+		}
+
+		node.Content = append(node.Content,
+			&yaml.Node{
+				Kind:        yaml.ScalarNode,
+				Tag:         "!!str",
+				Value:       key,
+				HeadComment: exampleComment(field.Type, field.Tags),
+			},
+			value,
+		)
+	}
+
+	return node, nil
+}
+
+func exampleValueNode(tagName string, t reflect.Type, defaultYAML string, secret bool) (*yaml.Node, error) {
+	if defaultYAML != "" {
+		// Parsing it into the field type first guarantees
+		// that the example only contains valid defaults:
+		err := yaml.Unmarshal([]byte(defaultYAML), reflect.New(t).Interface())
+		if err != nil {
+			return nil, fmt.Errorf(`error parsing "default" value as YAML: %w`, err)
+		}
+
+		var doc yaml.Node
+		err = yaml.Unmarshal([]byte(defaultYAML), &doc)
+		if err != nil {
+			return nil, err
+		}
+
+		// Blank defaults or defaults with only comments have no content:
+		if len(doc.Content) == 0 {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}, nil
+		}
+
+		return withScalarTag(doc.Content[0], t), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return exampleValueNode(tagName, t.Elem(), "", secret)
+
+	case reflect.Struct:
+		if isSecretType(t) {
+			return exampleValueNode(tagName, unwrapSecretType(t), "", secret)
+		}
+		return exampleStructNode(tagName, t, secret)
+
+	case reflect.Slice, reflect.Array:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if t.Elem().Kind() != reflect.Struct {
+			node.Style = yaml.FlowStyle
+			return node, nil
+		}
+
+		// For slices of structs we render a sample element
+		// so the user can see which keys each item accepts:
+		item, err := exampleStructNode(tagName, t.Elem(), secret)
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, item)
+		return node, nil
+
+	case reflect.Map:
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Style: yaml.FlowStyle}, nil
+	}
+
+	var node yaml.Node
+	err := node.Encode(reflect.Zero(t).Interface())
+	return withScalarTag(&node, t), err
+}
+
+// withScalarTag makes sure scalars keep the type of the field when
+// rendered on other formats, e.g. a default of "42" on a string field
+// should still be a string and a 0 on a float field still a float.
+func withScalarTag(node *yaml.Node, t reflect.Type) *yaml.Node {
+	if node.Kind != yaml.ScalarNode || node.Tag == "!!null" {
+		return node
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		node.Tag = "!!str"
+	case reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseInt(node.Value, 10, 64); err == nil {
+			// Otherwise it would be read back as an integer:
+			node.Value += ".0"
+		}
+		node.Tag = "!!float"
+	}
+	return node
+}
+
+// exampleComment builds the comment describing a field, e.g.:
+//
+//	The number of times we retry a request
+//	type: int, required, validate: >0,<=10
+func exampleComment(t reflect.Type, tags map[string]string) string {
+	attrs := []string{"type: " + describeType(t)}
+
+	var rules []string
+	for _, exp := range strings.Split(tags["validate"], ",") {
+		if exp == "" {
+			continue
+		}
+
+		if exp == "required" {
+			attrs = append(attrs, "required")
+			continue
+		}
+
+		rules = append(rules, exp)
+	}
+
+	if len(rules) > 0 {
+		attrs = append(attrs, "validate: "+strings.Join(rules, ","))
+	}
+
+	comment := strings.Join(attrs, ", ")
+	if tags["desc"] != "" {
+		comment = tags["desc"] + "\n" + comment
+	}
+
+	return comment
+}
+
+// describeType returns a human friendly name for the type of a field,
+// anonymous structs for instance are just described as "object".
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return describeType(t.Elem())
+	case reflect.Struct:
+		if t.Name() != "" {
+			return t.Name()
+		}
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "list of " + describeType(t.Elem())
+	case reflect.Map:
+		return "map of " + describeType(t.Key()) + " to " + describeType(t.Elem())
+	case reflect.Interface:
+		return "any"
+	}
+
+	return t.String()
+}
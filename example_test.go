@@ -0,0 +1,188 @@
+package kparse
+
+import (
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestGenerateExample(t *testing.T) {
+	type exampleConfig struct {
+		SecretKey int    `yaml:"secretKey" json:"secretKey" toml:"secretKey" validate:"required" desc:"Key used for signing tokens"`
+		BaseURL   string `yaml:"baseUrl" json:"baseUrl" toml:"baseUrl" default:"https://example.com"`
+
+		Address struct {
+			Street string `yaml:"street" json:"street" toml:"street" default:"defaultStreet"`
+			City   string `yaml:"city" json:"city" toml:"city"`
+		} `yaml:"address" json:"address" toml:"address"`
+
+		MaxRetries int      `yaml:"maxRetries" json:"maxRetries" toml:"maxRetries" validate:">0,<=10"`
+		Ratio      float64  `yaml:"ratio" json:"ratio" toml:"ratio"`
+		Domains    []string `yaml:"domains" json:"domains" toml:"domains" validate:"len>=1"`
+
+		Users []struct {
+			Name string `yaml:"name" json:"name" toml:"name" validate:"required"`
+		} `yaml:"users" json:"users" toml:"users"`
+
+		Cache int `yaml:"-" json:"-" toml:"-"`
+	}
+
+	tests := []struct {
+		desc               string
+		format             string
+		structPtr          any
+		expectedOutput     string
+		expectErrToContain []string
+	}{
+		{
+			desc:      "should generate commented yaml files",
+			format:    "yaml",
+			structPtr: &exampleConfig{},
+			expectedOutput: `# Key used for signing tokens
+# type: int, required
+secretKey: 0
+# type: string
+baseUrl: https://example.com
+# type: object
+address:
+  # type: string
+  street: defaultStreet
+  # type: string
+  city: ""
+# type: int, validate: >0,<=10
+maxRetries: 0
+# type: float64
+ratio: 0.0
+# type: list of string, validate: len>=1
+domains: []
+# type: list of object
+users:
+  - # type: string, required
+    name: ""
+`,
+		},
+		{
+			desc:      "should generate json files",
+			format:    "json",
+			structPtr: &exampleConfig{},
+			expectedOutput: `{
+  "secretKey": 0,
+  "baseUrl": "https://example.com",
+  "address": {
+    "street": "defaultStreet",
+    "city": ""
+  },
+  "maxRetries": 0,
+  "ratio": 0,
+  "domains": [],
+  "users": [
+    {
+      "name": ""
+    }
+  ]
+}
+`,
+		},
+		{
+			desc:      "should generate commented toml files",
+			format:    "toml",
+			structPtr: &exampleConfig{},
+			expectedOutput: `# Key used for signing tokens
+# type: int, required
+secretKey = 0
+# type: string
+baseUrl = "https://example.com"
+# type: int, validate: >0,<=10
+maxRetries = 0
+# type: float64
+ratio = 0.0
+# type: list of string, validate: len>=1
+domains = []
+
+# type: object
+[address]
+# type: string
+street = "defaultStreet"
+# type: string
+city = ""
+
+# type: list of object
+[[users]]
+# type: string, required
+name = ""
+`,
+		},
+		{
+			desc:   "should keep the type of the field on default values",
+			format: "json",
+			structPtr: &struct {
+				Port    string  `json:"port" default:"8080"`
+				Timeout float64 `json:"timeout" default:"30"`
+			}{},
+			expectedOutput: `{
+  "port": "8080",
+  "timeout": 30
+}
+`,
+		},
+		{
+			desc:   "should render blank defaults as empty strings",
+			format: "json",
+			structPtr: &struct {
+				Name    string `json:"name" default:" "`
+				Comment string `json:"comment" default:"# only a comment"`
+			}{},
+			expectedOutput: `{
+  "name": "",
+  "comment": ""
+}
+`,
+		},
+		{
+			desc:   "should leave out the defaults of secrets",
+			format: "json",
+			structPtr: &struct {
+				Password Secret[string] `json:"password" default:"hunter2"`
+				Token    string         `json:"token" secret:"true" default:"hunter2"`
+				Auth     struct {
+					Pin int `json:"pin" default:"4242"`
+				} `json:"auth" secret:"true"`
+			}{},
+			expectedOutput: `{
+  "password": "",
+  "token": "",
+  "auth": {
+    "pin": 0
+  }
+}
+`,
+		},
+		{
+			desc:   "should report invalid default values",
+			format: "yaml",
+			structPtr: &struct {
+				Port int `yaml:"port" default:"notAnInt"`
+			}{},
+			expectErrToContain: []string{"Port", "default"},
+		},
+		{
+			desc:               "should report unsupported formats",
+			format:             "xml",
+			structPtr:          &exampleConfig{},
+			expectErrToContain: []string{"unsupported", "xml"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			output, err := GenerateExample(test.structPtr, test.format)
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, string(output), test.expectedOutput)
+		})
+	}
+}
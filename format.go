@@ -0,0 +1,124 @@
+package kparse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/teamcollab-net/kparse/internal/tomlnode"
+	"gopkg.in/yaml.v3"
+)
+
+// Format normalizes a config file written in the given format,
+// which can be "yaml", "json" or "toml".
+//
+// The keys of every mapping are sorted and the file is re-indented
+// with two spaces, on YAML files the comments and all the documents
+// of the file are preserved. Mappings containing aliases keep their
+// key order, since sorting could move an alias before its anchor.
+//
+// On TOML files the simple keys of each table are written before its
+// sub-tables and the comments above and next to keys and table headers
+// are preserved, comments inside arrays and inline tables are not.
+func Format(file []byte, format string) ([]byte, error) {
+	if format == "toml" {
+		doc, err := tomlnode.Decode(file)
+		if err != nil {
+			return nil, err
+		}
+
+		sortNodeKeys(doc)
+		return encodeNodeAsTOML(doc)
+	}
+
+	if format != "yaml" && format != "json" {
+		return nil, fmt.Errorf("unsupported format: '%s', expected one of: yaml, json or toml", format)
+	}
+
+	if format == "json" {
+		// The YAML parser would also accept invalid JSON files and its
+		// error messages for JSON syntax errors are not very helpful:
+		var value any
+		err := json.Unmarshal(file, &value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Since JSON is a subset of YAML we can use the YAML parser for
+	// reading both formats, YAML files might have multiple documents:
+	var docs []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(file))
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if doc.Kind == 0 {
+			continue
+		}
+
+		sortNodeKeys(&doc)
+		docs = append(docs, &doc)
+	}
+
+	if len(docs) == 0 {
+		// Empty documents have nothing to be formatted:
+		return file, nil
+	}
+
+	if format == "json" {
+		return encodeNodeAsJSON(docs[0])
+	}
+	return encodeNodeAsYAML(docs...)
+}
+
+func sortNodeKeys(node *yaml.Node) {
+	for _, child := range node.Content {
+		sortNodeKeys(child)
+	}
+
+	// Moving the keys could write an alias before its anchor:
+	if node.Kind != yaml.MappingNode || containsAlias(node) {
+		return
+	}
+
+	type pair struct {
+		key   *yaml.Node
+		value *yaml.Node
+	}
+
+	pairs := make([]pair, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, pair{node.Content[i], node.Content[i+1]})
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].key.Value < pairs[j].key.Value
+	})
+
+	for i, p := range pairs {
+		node.Content[2*i] = p.key
+		node.Content[2*i+1] = p.value
+	}
+}
+
+func containsAlias(node *yaml.Node) bool {
+	if node.Kind == yaml.AliasNode {
+		return true
+	}
+
+	for _, child := range node.Content {
+		if containsAlias(child) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package kparse
+
+import (
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		desc               string
+		format             string
+		input              string
+		expectedOutput     string
+		expectErrToContain []string
+	}{
+		{
+			desc:   "should sort keys and keep comments on yaml files",
+			format: "yaml",
+			input: `
+foo: 1
+# comment about bar
+bar:
+    b: [1, 2]
+    a: 3
+`,
+			expectedOutput: `# comment about bar
+bar:
+  a: 3
+  b: [1, 2]
+foo: 1
+`,
+		},
+		{
+			desc:           "should keep all documents of yaml files",
+			format:         "yaml",
+			input:          "b: 1\na: 2\n---\nd: 3\nc: 4\n",
+			expectedOutput: "a: 2\nb: 1\n---\nc: 4\nd: 3\n",
+		},
+		{
+			desc:           "should not move aliases before their anchors",
+			format:         "yaml",
+			input:          "z: &x 1\na: *x\nlist:\n  d: 1\n  c: 2\n",
+			expectedOutput: "z: &x 1\na: *x\nlist:\n  c: 2\n  d: 1\n",
+		},
+		{
+			desc:           "should sort keys of json files",
+			format:         "json",
+			input:          `{"foo": 1, "bar": {"b": [1, 2], "a": "3"}}`,
+			expectedOutput: "{\n  \"bar\": {\n    \"a\": \"3\",\n    \"b\": [\n      1,\n      2\n    ]\n  },\n  \"foo\": 1\n}\n",
+		},
+		{
+			desc:               "should report invalid files",
+			format:             "json",
+			input:              `{"foo": [}`,
+			expectErrToContain: []string{"invalid character"},
+		},
+		{
+			desc:   "should sort keys and keep comments on toml files",
+			format: "toml",
+			input: `
+# comment about name
+name = "my app" # the app name
+port = 8_080
+
+[server]
+  tls.enabled = true
+  hosts = ["a.com",
+    "b.com"]
+
+# first backend
+[[backend]]
+url = "http://a.com"
+started = 1979-05-27T07:32:00Z
+
+[[backend]]
+url = "http://b.com"
+`,
+			expectedOutput: `# comment about name
+name = "my app" # the app name
+port = 8080
+
+# first backend
+[[backend]]
+started = 1979-05-27T07:32:00Z
+url = "http://a.com"
+
+[[backend]]
+url = "http://b.com"
+
+[server]
+hosts = ["a.com", "b.com"]
+
+[server.tls]
+enabled = true
+`,
+		},
+		{
+			desc:               "should report invalid toml files with their positions",
+			format:             "toml",
+			input:              "name = \"my app\"\nname = \"other\"\n",
+			expectErrToContain: []string{"line 2, column 1", "already defined"},
+		},
+		{
+			desc:               "should report unsupported formats",
+			format:             "ini",
+			input:              `foo = 1`,
+			expectErrToContain: []string{"unsupported", "ini"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			output, err := Format([]byte(test.input), test.format)
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, string(output), test.expectedOutput)
+		})
+	}
+}
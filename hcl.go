@@ -0,0 +1,381 @@
+package kparse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/vingarcia/structi"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"gopkg.in/yaml.v3"
+)
+
+func MustParseHCLFile(path string, targetStruct any, opts ...Option) {
+	err := ParseHCLFile(path, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ParseHCLFile parses an HCL file into the target struct, see ParseHCL.
+func ParseHCLFile(path string, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseHCLFile(path, targetStruct, opts...)
+}
+
+func MustParseHCL(file []byte, targetStruct any, opts ...Option) {
+	err := ParseHCL(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ParseHCL parses the contents of an HCL file into the target struct,
+// where the attributes and blocks are read from the `hcl` tag:
+//
+//	name = "my app"
+//
+//	database {
+//		port = 5433
+//	}
+//
+//	service "api" {
+//		replicas = 2
+//	}
+//
+// A block is parsed into a nested struct, repeated blocks into a slice
+// of structs and labeled blocks into a map of structs keyed by their
+// labels. Labels can also be read into fields with the `label` option,
+// e.g. `hcl:"name,label"`, which is useful for slices of blocks.
+//
+// Expressions are evaluated without variables or functions.
+func ParseHCL(file []byte, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseHCL(file, targetStruct, opts...)
+}
+
+func MustParseHCLFromReader(file io.Reader, targetStruct any, opts ...Option) {
+	err := ParseHCLFromReader(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func ParseHCLFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseHCLFromReader(file, targetStruct, opts...)
+}
+
+// ParseHCLFile works like the top-level ParseHCLFile using the settings of the Parser.
+func (p *Parser) ParseHCLFile(path string, targetStruct any, opts ...Option) (err error) {
+	// The path is used as the source name unless the caller set another one:
+	opts = append([]Option{WithSourceName(path)}, opts...)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, file.Close())
+	}()
+
+	return p.ParseHCLFromReader(file, targetStruct, opts...)
+}
+
+// ParseHCL works like the top-level ParseHCL using the settings of the Parser.
+func (p *Parser) ParseHCL(file []byte, targetStruct any, opts ...Option) error {
+	return p.ParseHCLFromReader(bytes.NewReader(file), targetStruct, opts...)
+}
+
+// ParseHCLFromReader works like the top-level ParseHCLFromReader using the settings of the Parser.
+func (p *Parser) ParseHCLFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	state := p.newParseState("hcl", opts)
+
+	parsed, diags := hclsyntax.ParseConfig(content, state.options.sourceName, hcl.InitialPos)
+	if diags.HasErrors() {
+		return state.hclError(diags)
+	}
+
+	// Targets other than structs, e.g. maps, have their blocks arranged by labels:
+	var t reflect.Type
+	if v := reflect.ValueOf(targetStruct); v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
+		t = v.Elem().Type()
+	}
+
+	tree, err := state.hclTree(t, parsed.Body.(*hclsyntax.Body), nil)
+	if err != nil {
+		return err
+	}
+
+	return state.parseRoot(targetStruct, mapDecoder(tree))
+}
+
+// hclTree arranges the attributes and blocks of an HCL body into nested
+// maps following the struct t, which decides how the blocks are parsed.
+// The labels not used as map keys are set on the fields with the `label`
+// option, e.g. `hcl:"name,label"`. If t is nil the blocks are arranged
+// by their labels and the labels are ignored.
+func (s *parseState) hclTree(t reflect.Type, body *hclsyntax.Body, labels []hclLabel) (map[string]LazyDecoder, error) {
+	var info structi.StructInfo
+	if t != nil {
+		var err error
+		info, err = structi.GetStructInfo(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tree := map[string]LazyDecoder{}
+	if t != nil {
+		var labelKeys []string
+		for _, field := range info.Fields {
+			options := strings.Split(field.Tags[s.tagName], ",")[1:]
+			if slices.Contains(options, "label") {
+				labelKeys = append(labelKeys, s.tagKey(field.Name, field.Tags))
+			}
+		}
+
+		if len(labels) != len(labelKeys) {
+			return nil, s.hclErrorf(body.SrcRange, "expected %d labels on the block but got %d", len(labelKeys), len(labels))
+		}
+		for i, key := range labelKeys {
+			tree[key] = labels[i].decoder()
+		}
+	}
+
+	for name, attr := range body.Attributes {
+		value, err := s.hclAttribute(attr)
+		if err != nil {
+			return nil, err
+		}
+		tree[name] = value
+	}
+
+	// The blocks are grouped by type keeping the order of the file:
+	var types []string
+	blocks := map[string][]*hclsyntax.Block{}
+	for _, block := range body.Blocks {
+		if _, found := body.Attributes[block.Type]; found {
+			return nil, s.hclErrorf(block.TypeRange, "'%s' is used both as an attribute and as a block", block.Type)
+		}
+		if blocks[block.Type] == nil {
+			types = append(types, block.Type)
+		}
+		blocks[block.Type] = append(blocks[block.Type], block)
+	}
+
+	for _, blockType := range types {
+		var fieldType reflect.Type
+		for _, field := range info.Fields {
+			key := s.tagKey(field.Name, field.Tags)
+			if key != "" && s.keysMatch(blockType, key) {
+				fieldType = field.Type
+				break
+			}
+		}
+
+		value, err := s.hclBlocks(fieldType, blocks[blockType], 0)
+		if err != nil {
+			return nil, err
+		}
+		tree[blockType] = value
+	}
+
+	return tree, nil
+}
+
+// hclBlocks returns a LazyDecoder for the blocks of the same type following
+// the type t, where the first usedLabels labels were already used as map keys.
+func (s *parseState) hclBlocks(t reflect.Type, blocks []*hclsyntax.Block, usedLabels int) (LazyDecoder, error) {
+	if t != nil {
+		if _, hooked := s.options.decodeHooks[t]; hooked || isSecretType(t) {
+			t = nil
+		}
+	}
+
+	switch {
+	case t != nil && t.Kind() == reflect.Struct:
+		if len(blocks) > 1 {
+			return nil, s.hclErrorf(
+				blocks[1].TypeRange, "duplicate '%s' block, it was already defined on line %d",
+				blocks[1].Type, blocks[0].TypeRange.Start.Line,
+			)
+		}
+		return s.hclBlock(t, blocks[0], usedLabels)
+
+	case t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Struct:
+		items := make([]LazyDecoder, 0, len(blocks))
+		for _, block := range blocks {
+			item, err := s.hclBlock(t.Elem(), block, usedLabels)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return sliceDecoder(items), nil
+
+	case t != nil && t.Kind() == reflect.Map:
+		return s.hclLabeledBlocks(t.Elem(), blocks, usedLabels)
+	}
+
+	// Without a struct field the blocks are arranged by their labels,
+	// and unlabeled blocks are parsed as maps or lists of maps:
+	if len(blocks[0].Labels) > usedLabels {
+		return s.hclLabeledBlocks(nil, blocks, usedLabels)
+	}
+	if len(blocks) == 1 {
+		return s.hclBlock(nil, blocks[0], usedLabels)
+	}
+
+	items := make([]LazyDecoder, 0, len(blocks))
+	for _, block := range blocks {
+		item, err := s.hclBlock(nil, block, usedLabels)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return sliceDecoder(items), nil
+}
+
+// hclLabeledBlocks arranges the blocks into a map keyed by their next
+// unused label, where the items are parsed following the type t.
+func (s *parseState) hclLabeledBlocks(t reflect.Type, blocks []*hclsyntax.Block, usedLabels int) (LazyDecoder, error) {
+	var keys []string
+	byKey := map[string][]*hclsyntax.Block{}
+	for _, block := range blocks {
+		if len(block.Labels) <= usedLabels {
+			return nil, s.hclErrorf(block.TypeRange, "missing label on the '%s' block", block.Type)
+		}
+
+		key := block.Labels[usedLabels]
+		if byKey[key] == nil {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], block)
+	}
+
+	m := make(map[string]LazyDecoder, len(keys))
+	for _, key := range keys {
+		group := byKey[key]
+		isStruct := t != nil && t.Kind() == reflect.Struct
+		if len(group) > 1 && (isStruct || t == nil && len(group[1].Labels) == usedLabels+1) {
+			return nil, s.hclErrorf(
+				group[1].TypeRange, "duplicate '%s' block with label '%s', it was already defined on line %d",
+				group[1].Type, key, group[0].TypeRange.Start.Line,
+			)
+		}
+
+		value, err := s.hclBlocks(t, group, usedLabels+1)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+
+	return mapDecoder(m), nil
+}
+
+func (s *parseState) hclBlock(t reflect.Type, block *hclsyntax.Block, usedLabels int) (LazyDecoder, error) {
+	var labels []hclLabel
+	if t != nil {
+		for i := usedLabels; i < len(block.Labels); i++ {
+			labels = append(labels, hclLabel{value: block.Labels[i], pos: block.LabelRanges[i].Start})
+		}
+	}
+
+	tree, err := s.hclTree(t, block.Body, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapDecoder(tree), nil
+}
+
+// hclAttribute evaluates the expression of the attribute, which is
+// then decoded as YAML so it accepts the same types as YAML files.
+func (s *parseState) hclAttribute(attr *hclsyntax.Attribute) (LazyDecoder, error) {
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return nil, s.hclError(diags)
+	}
+
+	rng := attr.Expr.Range()
+	content, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return nil, s.hclErrorf(rng, "error reading the value of '%s': %s", attr.Name, err)
+	}
+
+	var document yaml.Node
+	err = yaml.Unmarshal(content, &document)
+	if err != nil {
+		return nil, s.hclErrorf(rng, "error reading the value of '%s': %s", attr.Name, err)
+	}
+
+	// The JSON positions are meaningless, so the nested values
+	// report the position of the attribute instead:
+	node := document.Content[0]
+	setNodePosition(node, rng.Start)
+
+	return func(target any) error {
+		if probe, ok := target.(*originProbe); ok {
+			probe.line = rng.Start.Line
+			probe.column = rng.Start.Column
+			return nil
+		}
+
+		return node.Decode(target)
+	}, nil
+}
+
+func setNodePosition(node *yaml.Node, pos hcl.Pos) {
+	node.Line = pos.Line
+	node.Column = pos.Column
+	for _, child := range node.Content {
+		setNodePosition(child, pos)
+	}
+}
+
+type hclLabel struct {
+	value string
+	pos   hcl.Pos
+}
+
+func (l hclLabel) decoder() LazyDecoder {
+	return textValueDecoder(l.value, l.pos.Line, l.pos.Column)
+}
+
+// hclError converts the diagnostics of the HCL parser into an error
+// with the positions of each problem.
+func (s *parseState) hclError(diags hcl.Diagnostics) error {
+	var errs error
+	for _, diag := range diags {
+		if diag.Severity != hcl.DiagError {
+			continue
+		}
+
+		err := errors.New(diag.Summary)
+		if diag.Detail != "" {
+			err = fmt.Errorf("%s; %s", diag.Summary, diag.Detail)
+		}
+		if diag.Subject != nil {
+			err = positionError(s.options.sourceName, diag.Subject.Start.Line, diag.Subject.Start.Column, err)
+		}
+		errs = errors.Join(errs, err)
+	}
+
+	return errs
+}
+
+func (s *parseState) hclErrorf(rng hcl.Range, format string, args ...any) error {
+	return positionError(s.options.sourceName, rng.Start.Line, rng.Start.Column, fmt.Errorf(format, args...))
+}
@@ -0,0 +1,207 @@
+package kparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestParseHCL(t *testing.T) {
+	type Service struct {
+		Name     string `hcl:"name,label"`
+		Replicas int    `hcl:"replicas" default:"1" validate:">0"`
+	}
+
+	type Route struct {
+		Path    string `hcl:"path" validate:"required"`
+		Backend string `hcl:"backend"`
+	}
+
+	type Config struct {
+		Name     string   `hcl:"name" validate:"required"`
+		Debug    bool     `hcl:"debug"`
+		Domains  []string `hcl:"domains"`
+		Database struct {
+			Host    string        `hcl:"host" default:"localhost"`
+			Port    int           `hcl:"port" default:"5432"`
+			Timeout time.Duration `hcl:"timeout"`
+		} `hcl:"database"`
+		Services []Service          `hcl:"service"`
+		Routes   map[string]Route   `hcl:"route"`
+		Limits   map[string]float64 `hcl:"limits"`
+	}
+
+	tests := []struct {
+		desc               string
+		file               string
+		opts               []Option
+		expectedConfig     func(config *Config)
+		expectErrToContain []string
+	}{
+		{
+			desc: "should parse attributes and blocks into nested structs",
+			file: `
+name    = "my app"
+debug   = true
+domains = ["a.com", "b.com"]
+limits  = { cpu = 1.5, memory = 512 }
+
+# comments are ignored
+database {
+	port    = 5433
+	timeout = "5s"
+}
+
+service "api" {
+	replicas = 2 * 2
+}
+
+service "worker" {}
+
+route "home" {
+	path = "/"
+}
+
+route "api" {
+	path    = "/api"
+	backend = "api"
+}
+`,
+			expectedConfig: func(config *Config) {
+				config.Name = "my app"
+				config.Debug = true
+				config.Domains = []string{"a.com", "b.com"}
+				config.Limits = map[string]float64{"cpu": 1.5, "memory": 512}
+				config.Database.Host = "localhost"
+				config.Database.Port = 5433
+				config.Database.Timeout = 5 * time.Second
+				config.Services = []Service{
+					{Name: "api", Replicas: 4},
+					{Name: "worker", Replicas: 1},
+				}
+				config.Routes = map[string]Route{
+					"home": {Path: "/"},
+					"api":  {Path: "/api", Backend: "api"},
+				}
+			},
+		},
+		{
+			desc:               "should validate the values",
+			file:               "debug = true\n",
+			expectErrToContain: []string{"missing required field 'name'"},
+		},
+		{
+			desc:               "should validate the values of labeled blocks",
+			file:               "name = \"my app\"\nservice \"api\" {\n\treplicas = 0\n}\n",
+			expectErrToContain: []string{"Replicas", "> 0"},
+		},
+		{
+			desc:               "should validate the values of blocks parsed into maps",
+			file:               "name = \"my app\"\nroute \"home\" {\n\tbackend = \"web\"\n}\n",
+			expectErrToContain: []string{"error parsing key 'home'", "missing required field 'path'"},
+		},
+		{
+			desc:               "should report unknown attributes and blocks in strict mode",
+			file:               "name = \"my app\"\nversion = 2\nlogging {\n\tlevel = \"debug\"\n}\n",
+			opts:               []Option{WithStrict(true)},
+			expectErrToContain: []string{"unknown keys: 'logging', 'version'"},
+		},
+		{
+			desc:               "should report syntax errors with their position",
+			file:               "name = \"my app\"\ndatabase {\n\tport = \n}\n",
+			expectErrToContain: []string{"line 3, column 9: Invalid expression"},
+		},
+		{
+			desc:               "should report expressions with variables",
+			file:               "name = var.name\n",
+			expectErrToContain: []string{"line 1, column 8: Variables not allowed"},
+		},
+		{
+			desc:               "should report duplicate blocks",
+			file:               "name = \"my app\"\ndatabase {}\ndatabase {}\n",
+			expectErrToContain: []string{"line 3, column 1: duplicate 'database' block, it was already defined on line 2"},
+		},
+		{
+			desc:               "should report duplicate labeled blocks on maps",
+			file:               "name = \"my app\"\nroute \"home\" {\n\tpath = \"/\"\n}\nroute \"home\" {\n\tpath = \"/home\"\n}\n",
+			expectErrToContain: []string{"line 5, column 1: duplicate 'route' block with label 'home', it was already defined on line 2"},
+		},
+		{
+			desc:               "should report missing labels",
+			file:               "name = \"my app\"\nroute {\n\tpath = \"/\"\n}\n",
+			expectErrToContain: []string{"line 2, column 1: missing label on the 'route' block"},
+		},
+		{
+			desc:               "should report values of the wrong type",
+			file:               "name = \"my app\"\ndatabase {\n\tport = \"many\"\n}\n",
+			expectErrToContain: []string{"many"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var config Config
+			err := ParseHCL([]byte(test.file), &config, test.opts...)
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+
+			var expected Config
+			test.expectedConfig(&expected)
+			tt.AssertEqual(t, config, expected)
+		})
+	}
+
+	t.Run("should arrange labeled blocks by label on maps", func(t *testing.T) {
+		var config map[string]any
+		err := ParseHCL([]byte(`
+name = "my app"
+service "api" "v1" {
+	replicas = 2
+}
+service "api" "v2" {
+	replicas = 3
+}
+`), &config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config, map[string]any{
+			"name": "my app",
+			"service": map[string]any{
+				"api": map[string]any{
+					"v1": map[string]any{"replicas": 2},
+					"v2": map[string]any{"replicas": 3},
+				},
+			},
+		})
+	})
+
+	t.Run("should record the origins of the values", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.hcl")
+		err := os.WriteFile(path, []byte("name = \"my app\"\n\nservice \"api\" {\n  replicas = 2\n}\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config Config
+		var origins Origins
+		err = ParseHCLFile(path, &config, WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, origins["name"], Origin{Source: path, Line: 1, Column: 8})
+		tt.AssertEqual(t, origins["service[0].name"], Origin{Source: path, Line: 3, Column: 9})
+		tt.AssertEqual(t, origins["service[0].replicas"], Origin{Source: path, Line: 4, Column: 14})
+		tt.AssertEqual(t, origins["database.port"], Origin{Default: true})
+	})
+
+	t.Run("should report the file path on errors", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.hcl")
+		err := os.WriteFile(path, []byte("name = \"my app\"\ndatabase {}\ndatabase {}\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config Config
+		err = ParseHCLFile(path, &config)
+		tt.AssertErrContains(t, err, path+":3:1: duplicate 'database' block")
+	})
+}
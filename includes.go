@@ -0,0 +1,355 @@
+package kparse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeKey is the key used for including other files into a map.
+const includeKey = "$include"
+
+// includeTag is the YAML tag used for replacing a value by the contents of a file.
+const includeTag = "!include"
+
+const defaultIncludeMaxDepth = 10
+
+// WithIncludes enables the include directives, so config files can be
+// split into multiple files. Maps can include other files with the
+// `$include` key, which takes a path or a list of paths:
+//
+//	$include: ./common.yaml
+//	maxRetries: 5
+//
+// Where the included maps are merged and the other keys of the including
+// map override their values. And on YAML files any value can be replaced
+// by the contents of a file with the `!include` tag:
+//
+//	database: !include ./db.yaml
+//
+// Paths are relative to the including file, for the files being parsed that
+// is the directory of their source name, e.g. the path given to ParseYAMLFile.
+func WithIncludes(enabled bool) Option {
+	return func(opts *parseOptions) {
+		opts.includes = enabled
+	}
+}
+
+// WithIncludeMaxDepth limits how many levels of files can include other
+// files, which is 10 by default. Include cycles are always reported as errors.
+func WithIncludeMaxDepth(depth int) Option {
+	return func(opts *parseOptions) {
+		opts.includeMaxDepth = depth
+	}
+}
+
+// WithIncludeRoot only allows including files inside the given directory,
+// the files are read through an os.Root so symbolic links can't point
+// to files outside of it.
+func WithIncludeRoot(dir string) Option {
+	return func(opts *parseOptions) {
+		opts.includeRoot = dir
+	}
+}
+
+// WithIncludeFS reads the included files from fsys instead of the
+// disk, so only files inside fsys can be included. The paths of the
+// files being parsed are taken from their source names if they are
+// valid paths on fsys, otherwise they are considered to be on its root.
+func WithIncludeFS(fsys fs.FS) Option {
+	return func(opts *parseOptions) {
+		opts.includeFS = fsys
+	}
+}
+
+// includeSite describes where an include directive was found.
+type includeSite struct {
+	file   string
+	line   int
+	column int
+}
+
+func (s includeSite) String() string {
+	if s.line == 0 {
+		return s.file
+	}
+	return fmt.Sprintf("%s:%d:%d", s.file, s.line, s.column)
+}
+
+// includeError is returned with the chain of includes that led to the error.
+type includeError struct {
+	ref   string
+	chain []includeSite
+	err   error
+}
+
+func (e *includeError) Error() string {
+	sites := make([]string, len(e.chain))
+	for i, site := range e.chain {
+		sites[i] = site.String()
+	}
+
+	return fmt.Sprintf("error including '%s' (include chain: %s): %s", e.ref, strings.Join(sites, " -> "), e.err)
+}
+
+func (e *includeError) Unwrap() error {
+	return e.err
+}
+
+// tagProbe reads the tag of YAML values, JSON values have no tags.
+type tagProbe struct {
+	tag   string
+	value string
+	line  int
+	col   int
+}
+
+func (p *tagProbe) UnmarshalYAML(node *yaml.Node) error {
+	p.tag = node.Tag
+	p.value = node.Value
+	p.line = node.Line
+	p.col = node.Column
+	return nil
+}
+
+func (p *tagProbe) UnmarshalJSON([]byte) error {
+	return nil
+}
+
+// resolveIncludes replaces all the include directives found inside
+// the value, file is the path of the file containing the value.
+func (s *parseState) resolveIncludes(value LazyDecoder, file string, chain []includeSite) (LazyDecoder, error) {
+	resolved, _, err := s.resolveValueIncludes(value, file, chain)
+	return resolved, err
+}
+
+// resolveValueIncludes returns the resolved value and whether it
+// changed, so values without includes keep their original decoders.
+func (s *parseState) resolveValueIncludes(value LazyDecoder, file string, chain []includeSite) (_ LazyDecoder, changed bool, _ error) {
+	if value == nil {
+		return nil, false, nil
+	}
+
+	var probe tagProbe
+	_ = value.Decode(&probe)
+	if probe.tag == includeTag {
+		site := includeSite{file: file, line: probe.line, column: probe.col}
+		included, err := s.include(probe.value, append(chain, site))
+		return included, true, err
+	}
+
+	var m map[string]LazyDecoder
+	if value.Decode(&m) == nil && m != nil {
+		return s.resolveMapIncludes(value, m, file, chain)
+	}
+
+	var items []LazyDecoder
+	if value.Decode(&items) == nil && items != nil {
+		changed := false
+		for i, item := range items {
+			resolved, itemChanged, err := s.resolveValueIncludes(item, file, chain)
+			if err != nil {
+				return nil, false, err
+			}
+			items[i] = resolved
+			changed = changed || itemChanged
+		}
+
+		if changed {
+			return sliceDecoder(items), true, nil
+		}
+	}
+
+	return value, false, nil
+}
+
+func (s *parseState) resolveMapIncludes(
+	value LazyDecoder,
+	m map[string]LazyDecoder,
+	file string,
+	chain []includeSite,
+) (_ LazyDecoder, changed bool, _ error) {
+	for key, item := range m {
+		if key == includeKey {
+			continue
+		}
+
+		resolved, itemChanged, err := s.resolveValueIncludes(item, file, chain)
+		if err != nil {
+			return nil, false, err
+		}
+		m[key] = resolved
+		changed = changed || itemChanged
+	}
+
+	directive, found := m[includeKey]
+	if !found {
+		if changed {
+			return mapDecoder(m), true, nil
+		}
+		return value, false, nil
+	}
+	delete(m, includeKey)
+
+	origin := originOf(file, directive)
+	site := includeSite{file: file, line: origin.Line, column: origin.Column}
+
+	var refs []string
+	var ref string
+	if directive.Decode(&ref) == nil {
+		refs = []string{ref}
+	} else if directive.Decode(&refs) != nil {
+		return nil, false, fmt.Errorf("at %s: the '%s' key expects a path or a list of paths", site, includeKey)
+	}
+
+	var merged LazyDecoder
+	for _, ref := range refs {
+		included, err := s.include(ref, append(chain, site))
+		if err != nil {
+			return nil, false, err
+		}
+
+		merged = mergeDecoders(merged, included)
+	}
+
+	// The keys of the including map override the included values:
+	return mergeDecoders(merged, mapDecoder(m)), true, nil
+}
+
+// include loads the file referenced by the last include site of the chain.
+func (s *parseState) include(ref string, chain []includeSite) (LazyDecoder, error) {
+	includingFile := chain[len(chain)-1].file
+
+	maxDepth := s.options.includeMaxDepth
+	if maxDepth == 0 {
+		maxDepth = defaultIncludeMaxDepth
+	}
+	if len(chain) > maxDepth {
+		return nil, &includeError{ref, chain, fmt.Errorf("include depth limit of %d exceeded", maxDepth)}
+	}
+
+	includePath, file, err := s.readInclude(includingFile, ref)
+	if err != nil {
+		return nil, &includeError{ref, chain, err}
+	}
+
+	for _, site := range chain {
+		if filepath.Clean(site.file) == includePath {
+			return nil, &includeError{ref, chain, fmt.Errorf("include cycle detected, '%s' includes itself", includePath)}
+		}
+	}
+
+	format, _ := formatFromPath(includePath)
+
+	var value LazyDecoder
+	if format == "json" {
+		value, err = decodeJSONSource(file)
+	} else {
+		value, err = decodeYAMLSource(file)
+	}
+	if err != nil {
+		return nil, &includeError{ref, chain, err}
+	}
+
+	resolved, err := s.resolveIncludes(value, includePath, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	return withSource(includePath, resolved), nil
+}
+
+// readInclude resolves the path of an included file relative to the
+// including file, checking if it is allowed, and reads its contents.
+func (s *parseState) readInclude(includingFile string, ref string) (string, []byte, error) {
+	if _, err := formatFromPath(ref); err != nil {
+		return "", nil, err
+	}
+
+	if s.options.includeFS != nil {
+		dir := "."
+		if fs.ValidPath(includingFile) {
+			dir = path.Dir(includingFile)
+		}
+
+		includePath := path.Join(dir, ref)
+		if !fs.ValidPath(includePath) {
+			return "", nil, fmt.Errorf("path is outside of the include file system")
+		}
+
+		file, err := fs.ReadFile(s.options.includeFS, includePath)
+		return includePath, file, err
+	}
+
+	includePath := ref
+	if !filepath.IsAbs(includePath) {
+		includePath = filepath.Join(filepath.Dir(includingFile), ref)
+	}
+
+	if s.options.includeRoot != "" {
+		rel, inside, err := relativeToDir(s.options.includeRoot, includePath)
+		if err != nil {
+			return "", nil, err
+		}
+		if !inside {
+			return "", nil, fmt.Errorf("path is outside of the include root '%s'", s.options.includeRoot)
+		}
+
+		file, err := readFileInRoot(s.options.includeRoot, rel)
+		return includePath, file, err
+	}
+
+	file, err := os.ReadFile(includePath)
+	return includePath, file, err
+}
+
+// relativeToDir returns the path of file relative to dir,
+// also reporting if the file is inside of dir.
+func relativeToDir(dir string, file string) (rel string, inside bool, _ error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false, err
+	}
+
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return "", false, err
+	}
+
+	rel, err = filepath.Rel(absDir, absFile)
+	if err != nil {
+		return "", false, nil
+	}
+
+	inside = rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	return rel, inside, nil
+}
+
+// readFileInRoot reads the file through an os.Root, so symbolic
+// links can't be used for reading files outside of the dir.
+func readFileInRoot(dir string, rel string) (_ []byte, err error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, root.Close())
+	}()
+
+	file, err := root.Open(rel)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, file.Close())
+	}()
+
+	return io.ReadAll(file)
+}
@@ -0,0 +1,229 @@
+package kparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestIncludes(t *testing.T) {
+	type Database struct {
+		Host string `yaml:"host" validate:"required"`
+		Port int    `yaml:"port" default:"5432"`
+	}
+
+	type Config struct {
+		MaxRetries int        `yaml:"maxRetries" default:"3"`
+		Domains    []string   `yaml:"domains"`
+		Database   Database   `yaml:"database"`
+		Replicas   []Database `yaml:"replicas"`
+	}
+
+	tests := []struct {
+		desc               string
+		files              map[string]string
+		opts               []Option
+		expectedConfig     Config
+		expectErrToContain []string
+	}{
+		{
+			desc: "should merge the included maps and override them with the including map",
+			files: map[string]string{
+				"config.yaml":  "$include: [./common.yaml, ./conf/db.json]\nmaxRetries: 5\n",
+				"common.yaml":  "maxRetries: 1\ndomains: [example.com]\ndatabase:\n  host: localhost\n",
+				"conf/db.json": `{"database": {"port": 5433}}`,
+			},
+			expectedConfig: Config{
+				MaxRetries: 5,
+				Domains:    []string{"example.com"},
+				Database:   Database{Host: "localhost", Port: 5433},
+			},
+		},
+		{
+			desc: "should replace values tagged with !include",
+			files: map[string]string{
+				"config.yaml":        "database: !include ./conf/db.yaml\nreplicas:\n  - !include ./conf/replica.yaml\n",
+				"conf/db.yaml":       "$include: ./defaults.yaml\nhost: db.example.com\n",
+				"conf/defaults.yaml": "port: 6000\n",
+				"conf/replica.yaml":  "host: replica.example.com\n",
+			},
+			expectedConfig: Config{
+				MaxRetries: 3,
+				Database:   Database{Host: "db.example.com", Port: 6000},
+				Replicas:   []Database{{Host: "replica.example.com", Port: 5432}},
+			},
+		},
+		{
+			desc: "should validate the included values",
+			files: map[string]string{
+				"config.yaml": "database: !include ./db.yaml\n",
+				"db.yaml":     "port: 5433\n",
+			},
+			expectErrToContain: []string{"missing required field 'host'"},
+		},
+		{
+			desc: "should report the include chain of missing files",
+			files: map[string]string{
+				"config.yaml": "maxRetries: 5\n$include: ./a.yaml\n",
+				"a.yaml":      "database: !include ./missing.yaml\n",
+			},
+			expectErrToContain: []string{
+				"error including './missing.yaml'",
+				"config.yaml:2:11 -> ",
+				"a.yaml:1:11)",
+				"no such file or directory",
+			},
+		},
+		{
+			desc: "should report include cycles",
+			files: map[string]string{
+				"config.yaml": "$include: ./a.yaml\n",
+				"a.yaml":      "$include: ./config.yaml\n",
+			},
+			expectErrToContain: []string{"include cycle detected", "config.yaml' includes itself"},
+		},
+		{
+			desc: "should limit the include depth",
+			files: map[string]string{
+				"config.yaml": "$include: ./a.yaml\n",
+				"a.yaml":      "$include: ./b.yaml\n",
+				"b.yaml":      "maxRetries: 5\n",
+			},
+			opts:               []Option{WithIncludeMaxDepth(1)},
+			expectErrToContain: []string{"error including './b.yaml'", "include depth limit of 1 exceeded"},
+		},
+		{
+			desc: "should reject files outside of the include root",
+			files: map[string]string{
+				"config/config.yaml": "$include: ../secrets.yaml\n",
+				"secrets.yaml":       "maxRetries: 5\n",
+			},
+			expectErrToContain: []string{"error including '../secrets.yaml'", "path is outside of the include root"},
+		},
+		{
+			desc: "should reject invalid include directives",
+			files: map[string]string{
+				"config.yaml": "$include:\n  path: ./a.yaml\n",
+			},
+			expectErrToContain: []string{"config.yaml:2:3: the '$include' key expects a path or a list of paths"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range test.files {
+				path := filepath.Join(dir, name)
+				err := os.MkdirAll(filepath.Dir(path), 0o755)
+				tt.AssertNoErr(t, err)
+				err = os.WriteFile(path, []byte(content), 0o644)
+				tt.AssertNoErr(t, err)
+			}
+
+			configPath := filepath.Join(dir, "config.yaml")
+			if _, found := test.files["config/config.yaml"]; found {
+				configPath = filepath.Join(dir, "config", "config.yaml")
+			}
+
+			opts := append([]Option{WithIncludes(true), WithIncludeRoot(filepath.Dir(configPath))}, test.opts...)
+
+			var config Config
+			err := ParseYAMLFile(configPath, &config, opts...)
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, config, test.expectedConfig)
+		})
+	}
+
+	t.Run("should reject symbolic links to files outside of the include root", func(t *testing.T) {
+		dir := t.TempDir()
+		root := filepath.Join(dir, "config")
+		err := os.MkdirAll(root, 0o755)
+		tt.AssertNoErr(t, err)
+		err = os.WriteFile(filepath.Join(dir, "outside.yaml"), []byte("maxRetries: 5\ndatabase:\n  host: localhost\n"), 0o644)
+		tt.AssertNoErr(t, err)
+		err = os.Symlink(filepath.Join(dir, "outside.yaml"), filepath.Join(root, "link.yaml"))
+		tt.AssertNoErr(t, err)
+		configPath := filepath.Join(root, "config.yaml")
+		err = os.WriteFile(configPath, []byte("$include: ./link.yaml\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config Config
+		err = ParseYAMLFile(configPath, &config, WithIncludes(true), WithIncludeRoot(root))
+		tt.AssertErrContains(t, err, "error including './link.yaml'", "path escapes from parent")
+
+		// Without the include root symbolic links are followed:
+		err = ParseYAMLFile(configPath, &config, WithIncludes(true))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.MaxRetries, 5)
+	})
+
+	t.Run("should read the included files from an fs.FS", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"conf/config.yaml": {Data: []byte("database: !include ./db.yaml\n")},
+			"conf/db.yaml":     {Data: []byte("host: db.example.com\n$include: ../common.yaml\n")},
+			"common.yaml":      {Data: []byte("port: 6000\n")},
+		}
+
+		file, err := fsys.ReadFile("conf/config.yaml")
+		tt.AssertNoErr(t, err)
+
+		var config Config
+		err = ParseYAML(file, &config, WithIncludes(true), WithIncludeFS(fsys), WithSourceName("conf/config.yaml"))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.Database, Database{Host: "db.example.com", Port: 6000})
+
+		fsys["conf/db.yaml"] = &fstest.MapFile{Data: []byte("$include: ../../outside.yaml\n")}
+		err = ParseYAML(file, &config, WithIncludes(true), WithIncludeFS(fsys), WithSourceName("conf/config.yaml"))
+		tt.AssertErrContains(t, err, "path is outside of the include file system")
+	})
+
+	t.Run("should keep the precision of included json numbers", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"config.yaml": {Data: []byte("$include: ./limits.json\nids:\n  - !include ./id.json\n")},
+			"limits.json": {Data: []byte(`{"limits": {"b": 9007199254740993}}`)},
+			"id.json":     {Data: []byte(`9007199254740993`)},
+		}
+
+		var config struct {
+			Limits map[string]int64 `yaml:"limits"`
+			IDs    []int64          `yaml:"ids"`
+		}
+		err := ParseYAML(fsys["config.yaml"].Data, &config, WithIncludes(true), WithIncludeFS(fsys), WithSourceName("config.yaml"))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.Limits, map[string]int64{"b": 9007199254740993})
+		tt.AssertEqual(t, config.IDs, []int64{9007199254740993})
+	})
+
+	t.Run("should record the origins of the included values", func(t *testing.T) {
+		dir := t.TempDir()
+		configPath := filepath.Join(dir, "config.yaml")
+		dbPath := filepath.Join(dir, "db.yaml")
+		err := os.WriteFile(configPath, []byte("maxRetries: 5\ndatabase: !include ./db.yaml\n"), 0o644)
+		tt.AssertNoErr(t, err)
+		err = os.WriteFile(dbPath, []byte("host: localhost\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config Config
+		var origins Origins
+		err = ParseYAMLFile(configPath, &config, WithIncludes(true), WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, origins["maxRetries"], Origin{Source: configPath, Line: 1, Column: 13})
+		tt.AssertEqual(t, origins["database.host"], Origin{Source: dbPath, Line: 1, Column: 7})
+	})
+
+	t.Run("should ignore include directives unless enabled", func(t *testing.T) {
+		var config struct {
+			Include string `yaml:"$include"`
+		}
+		err := ParseYAML([]byte("$include: ./a.yaml\n"), &config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.Include, "./a.yaml")
+	})
+}
@@ -0,0 +1,176 @@
+package kparse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+func MustParseINIFile(path string, targetStruct any, opts ...Option) {
+	err := ParseINIFile(path, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ParseINIFile parses an INI file into the target struct, see ParseINI.
+func ParseINIFile(path string, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseINIFile(path, targetStruct, opts...)
+}
+
+func MustParseINI(file []byte, targetStruct any, opts ...Option) {
+	err := ParseINI(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ParseINI parses the contents of an INI file into the target struct,
+// where the keys are read from the `ini` tag and each section, e.g.
+// `[database]`, is parsed into a nested struct. Dotted section names,
+// e.g. `[database.replica]`, are parsed into structs nested further.
+//
+// Values are converted to the types of the fields, lists are written as
+// comma separated values, and comments start with `;` or `#`.
+func ParseINI(file []byte, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseINI(file, targetStruct, opts...)
+}
+
+func MustParseINIFromReader(file io.Reader, targetStruct any, opts ...Option) {
+	err := ParseINIFromReader(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func ParseINIFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseINIFromReader(file, targetStruct, opts...)
+}
+
+// ParseINIFile works like the top-level ParseINIFile using the settings of the Parser.
+func (p *Parser) ParseINIFile(path string, targetStruct any, opts ...Option) (err error) {
+	// The path is used as the source name unless the caller set another one:
+	opts = append([]Option{WithSourceName(path)}, opts...)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, file.Close())
+	}()
+
+	return p.ParseINIFromReader(file, targetStruct, opts...)
+}
+
+// ParseINI works like the top-level ParseINI using the settings of the Parser.
+func (p *Parser) ParseINI(file []byte, targetStruct any, opts ...Option) error {
+	return p.ParseINIFromReader(bytes.NewReader(file), targetStruct, opts...)
+}
+
+// ParseINIFromReader works like the top-level ParseINIFromReader using the settings of the Parser.
+func (p *Parser) ParseINIFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	tree, err := parseINI(string(content))
+	if err != nil {
+		return err
+	}
+
+	return p.newParseState("ini", opts).parseRoot(targetStruct, tree.decoder())
+}
+
+func parseINI(content string) (textTree, error) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	tree := textTree{}
+	var section []string
+	for i, line := range lines {
+		lineNumber := i + 1
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == ';' || trimmed[0] == '#' {
+			continue
+		}
+
+		if trimmed[0] == '[' {
+			end := strings.IndexByte(trimmed, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("line %d: missing ']' on the section '%s'", lineNumber, trimmed)
+			}
+			if !isCommentOrEmpty(trimmed[end+1:]) {
+				return nil, fmt.Errorf("line %d: unexpected characters after the section: '%s'", lineNumber, trimmed[end+1:])
+			}
+
+			section = nil
+			for _, name := range strings.Split(trimmed[1:end], ".") {
+				name = strings.TrimSpace(name)
+				if name == "" {
+					return nil, fmt.Errorf("line %d: invalid section name '%s'", lineNumber, trimmed[1:end])
+				}
+				section = append(section, name)
+			}
+
+			// Sections are created even without keys:
+			_, err := tree.section(section)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			continue
+		}
+
+		sep := strings.IndexAny(line, "=:")
+		if sep == -1 {
+			return nil, fmt.Errorf("line %d: expected 'key = value' but got '%s'", lineNumber, trimmed)
+		}
+
+		key := strings.TrimSpace(line[:sep])
+		if key == "" {
+			return nil, fmt.Errorf("line %d: missing key before '%c'", lineNumber, line[sep])
+		}
+
+		start := sep + 1
+		for start < len(line) && (line[start] == ' ' || line[start] == '\t') {
+			start++
+		}
+
+		value := line[start:]
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			unquoted, remainder, closed := readQuotedValue(value)
+			if !closed {
+				return nil, fmt.Errorf("line %d: missing closing quote on the value of '%s'", lineNumber, key)
+			}
+			if !isCommentOrEmpty(remainder) {
+				return nil, fmt.Errorf("line %d: unexpected characters after the closing quote: '%s'", lineNumber, strings.TrimSpace(remainder))
+			}
+			value = unquoted
+		} else {
+			// Unquoted values end at the comments:
+			for _, comment := range []string{" ;", "\t;", " #", "\t#"} {
+				if index := strings.Index(value, comment); index != -1 {
+					value = value[:index]
+				}
+			}
+			value = strings.TrimSpace(value)
+		}
+
+		err := tree.set(append(slices.Clip(section), key), textValueDecoder(value, lineNumber, start+1))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+
+	return tree, nil
+}
+
+func isCommentOrEmpty(text string) bool {
+	text = strings.TrimSpace(text)
+	return text == "" || text[0] == ';' || text[0] == '#'
+}
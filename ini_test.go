@@ -0,0 +1,123 @@
+package kparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestParseINI(t *testing.T) {
+	type Replica struct {
+		Host string `ini:"host"`
+	}
+
+	type Config struct {
+		Name     string `ini:"name" validate:"required"`
+		Debug    bool   `ini:"debug"`
+		Database struct {
+			Host    string        `ini:"host" default:"localhost"`
+			Port    int           `ini:"port" default:"5432"`
+			Timeout time.Duration `ini:"timeout"`
+			Tags    []string      `ini:"tags"`
+			Replica Replica       `ini:"replica"`
+		} `ini:"database"`
+	}
+
+	tests := []struct {
+		desc               string
+		file               string
+		opts               []Option
+		expectedConfig     func(config *Config)
+		expectErrToContain []string
+	}{
+		{
+			desc: "should parse sections into nested structs",
+			file: `; global settings
+name = my app
+debug: true
+
+[database]
+port = 5433 ; inline comment
+timeout = 5s
+tags = primary, eu-west
+# full line comment
+
+[database.replica]
+host = "replica ; not a comment"
+`,
+			expectedConfig: func(config *Config) {
+				config.Name = "my app"
+				config.Debug = true
+				config.Database.Host = "localhost"
+				config.Database.Port = 5433
+				config.Database.Timeout = 5 * time.Second
+				config.Database.Tags = []string{"primary", "eu-west"}
+				config.Database.Replica.Host = "replica ; not a comment"
+			},
+		},
+		{
+			desc:               "should validate the values",
+			file:               "debug = true\n[database.replica]\nhost = replica\n",
+			expectErrToContain: []string{"missing required field 'name'"},
+		},
+		{
+			desc:               "should report values of the wrong type",
+			file:               "name = my app\n[database]\nport = many\n",
+			expectErrToContain: []string{"port"},
+		},
+		{
+			desc:               "should report unknown keys in strict mode",
+			file:               "name = my app\n[logging]\nlevel = debug\n",
+			opts:               []Option{WithStrict(true)},
+			expectErrToContain: []string{"unknown keys: 'logging'"},
+		},
+		{
+			desc:               "should report invalid lines",
+			file:               "name = my app\n[database\n",
+			expectErrToContain: []string{"line 2: missing ']' on the section '[database'"},
+		},
+		{
+			desc:               "should report lines without values",
+			file:               "name = my app\ndebug\n",
+			expectErrToContain: []string{"line 2: expected 'key = value' but got 'debug'"},
+		},
+		{
+			desc:               "should report keys used as values and sections",
+			file:               "database = postgres\n[database]\nport = 5433\n",
+			expectErrToContain: []string{"line 2: key 'database' is used both for a value and for nested keys"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var config Config
+			err := ParseINI([]byte(test.file), &config, test.opts...)
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+
+			var expected Config
+			test.expectedConfig(&expected)
+			tt.AssertEqual(t, config, expected)
+		})
+	}
+
+	t.Run("should record the origins of the values", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.ini")
+		err := os.WriteFile(path, []byte("name = my app\n\n[database]\n  port=5433\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config Config
+		var origins Origins
+		err = ParseINIFile(path, &config, WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, origins["name"], Origin{Source: path, Line: 1, Column: 8})
+		tt.AssertEqual(t, origins["database.port"], Origin{Source: path, Line: 4, Column: 8})
+		tt.AssertEqual(t, origins["database.host"], Origin{Default: true})
+	})
+}
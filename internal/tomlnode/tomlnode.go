@@ -0,0 +1,323 @@
+// Package tomlnode reads TOML documents into yaml.Node trees, so the
+// code written for YAML nodes, e.g. sorting keys or reporting the line
+// and column of invalid values, also works on TOML files.
+package tomlnode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// Decode parses a TOML document into a yaml.Node of kind DocumentNode
+// whose only child is the root table.
+//
+// Every node keeps the line and column of the value on the TOML file,
+// the comments written above keys and tables are kept as head comments
+// and the comments on the same line as line comments of the keys.
+// Comments inside arrays and inline tables are discarded.
+func Decode(file []byte) (*yaml.Node, error) {
+	// The parser below doesn't check the semantics of the document,
+	// e.g. duplicate keys, so we let the TOML decoder do it first:
+	var value map[string]any
+	err := toml.Unmarshal(file, &value)
+	if err != nil {
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			line, column := decodeErr.Position()
+			return nil, fmt.Errorf("line %d, column %d: %w", line, column, err)
+		}
+		return nil, err
+	}
+
+	d := decoder{
+		parser: &unstable.Parser{KeepComments: true},
+		root:   &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: 1, Column: 1},
+	}
+	d.parser.Reset(file)
+
+	table := d.root
+	var comments []string
+	for d.parser.NextExpression() {
+		expr := d.parser.Expression()
+		switch expr.Kind {
+		case unstable.Comment:
+			comments = append(comments, string(expr.Data))
+			continue
+		case unstable.KeyValue:
+			key, err := d.keyValue(table, expr)
+			if err != nil {
+				return nil, err
+			}
+			key.HeadComment = strings.Join(comments, "\n")
+			key.LineComment = lineComment(expr)
+		case unstable.Table, unstable.ArrayTable:
+			key, node, err := d.table(expr)
+			if err != nil {
+				return nil, err
+			}
+			table = node
+
+			// The items of arrays of tables share the same key, so
+			// the comments of their headers are kept on the items:
+			commented := key
+			if expr.Kind == unstable.ArrayTable {
+				commented = node
+			}
+			commented.HeadComment = strings.Join(comments, "\n")
+			commented.LineComment = lineComment(expr)
+		}
+		comments = nil
+	}
+	if d.parser.Error() != nil {
+		return nil, d.parser.Error()
+	}
+	d.root.FootComment = strings.Join(comments, "\n")
+
+	return &yaml.Node{
+		Kind:    yaml.DocumentNode,
+		Line:    1,
+		Column:  1,
+		Content: []*yaml.Node{d.root},
+	}, nil
+}
+
+type decoder struct {
+	parser *unstable.Parser
+	root   *yaml.Node
+}
+
+// table finds or creates the table named on a `[table]` or `[[table]]`
+// header, returning the node of its last key and the table itself.
+func (d decoder) table(expr *unstable.Node) (key *yaml.Node, table *yaml.Node, _ error) {
+	parts := d.keyParts(expr)
+
+	parent, err := d.walk(d.root, parts[:len(parts)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	last := parts[len(parts)-1]
+	key, value := child(parent, string(last.Data))
+	if expr.Kind == unstable.Table {
+		if value == nil {
+			key, value = d.add(parent, last, d.mapping(last))
+		}
+		return key, lastItem(value), nil
+	}
+
+	if value == nil {
+		key, value = d.add(parent, last, &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"})
+		value.Line, value.Column = key.Line, key.Column
+	}
+	item := d.mapping(last)
+	value.Content = append(value.Content, item)
+
+	return key, item, nil
+}
+
+// keyValue adds a `key = value` expression to the table,
+// returning the node of its last key.
+func (d decoder) keyValue(table *yaml.Node, expr *unstable.Node) (*yaml.Node, error) {
+	parts := d.keyParts(expr)
+
+	parent, err := d.walk(table, parts[:len(parts)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	line, column := d.valuePosition(expr)
+	value, err := d.value(expr.Value(), line, column)
+	if err != nil {
+		return nil, err
+	}
+
+	key, _ := d.add(parent, parts[len(parts)-1], value)
+	return key, nil
+}
+
+// walk follows the parts of a dotted key starting on the given table,
+// creating the tables that don't exist yet.
+func (d decoder) walk(table *yaml.Node, parts []*unstable.Node) (*yaml.Node, error) {
+	for _, part := range parts {
+		_, value := child(table, string(part.Data))
+		if value == nil {
+			_, value = d.add(table, part, d.mapping(part))
+		}
+
+		table = lastItem(value)
+		if table.Kind != yaml.MappingNode {
+			// Unreachable since the TOML decoder checks the document first:
+			line, column := d.position(part)
+			return nil, fmt.Errorf("line %d, column %d: key '%s' is not a table", line, column, part.Data)
+		}
+	}
+
+	return table, nil
+}
+
+func (d decoder) value(node *unstable.Node, line int, column int) (*yaml.Node, error) {
+	if node.Raw.Length > 0 {
+		line, column = d.position(node)
+	}
+
+	switch node.Kind {
+	case unstable.InlineTable:
+		table := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: line, Column: column}
+		it := node.Children()
+		for it.Next() {
+			if it.Node().Kind != unstable.KeyValue {
+				continue
+			}
+
+			_, err := d.keyValue(table, it.Node())
+			if err != nil {
+				return nil, err
+			}
+		}
+		return table, nil
+
+	case unstable.Array:
+		array := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: line, Column: column}
+		it := node.Children()
+		for it.Next() {
+			if it.Node().Kind == unstable.Comment {
+				continue
+			}
+
+			// Arrays don't keep their positions, so arrays of arrays
+			// are reported at the position of the parent array:
+			item, err := d.value(it.Node(), line, column)
+			if err != nil {
+				return nil, err
+			}
+			array.Content = append(array.Content, item)
+		}
+		return array, nil
+	}
+
+	scalar, err := scalarNode(node)
+	if err != nil {
+		line, column = d.position(node)
+		return nil, fmt.Errorf("line %d, column %d: %w", line, column, err)
+	}
+	scalar.Line, scalar.Column = line, column
+
+	return scalar, nil
+}
+
+func scalarNode(node *unstable.Node) (*yaml.Node, error) {
+	data := string(node.Data)
+	switch node.Kind {
+	case unstable.String:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: data}, nil
+
+	case unstable.Bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: data}, nil
+
+	case unstable.Integer:
+		// Base 0 also accepts the 0x, 0o and 0b prefixes used by TOML:
+		i, err := strconv.ParseInt(strings.ReplaceAll(data, "_", ""), 0, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(i, 10)}, nil
+
+	case unstable.Float:
+		value := strings.ReplaceAll(data, "_", "")
+		switch strings.TrimPrefix(value, "+") {
+		case "inf":
+			value = ".inf"
+		case "-inf":
+			value = "-.inf"
+		case "nan", "-nan":
+			value = ".nan"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: value}, nil
+
+	case unstable.LocalDate, unstable.LocalTime, unstable.LocalDateTime, unstable.DateTime:
+		// Dates are kept as written since YAML has no local dates or times:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: data}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected TOML value of kind %v", node.Kind)
+}
+
+// add appends the key and its value to the table.
+func (d decoder) add(table *yaml.Node, part *unstable.Node, value *yaml.Node) (key *yaml.Node, _ *yaml.Node) {
+	line, column := d.position(part)
+	key = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: string(part.Data), Line: line, Column: column}
+	table.Content = append(table.Content, key, value)
+	return key, value
+}
+
+// mapping creates a table defined implicitly by one of the parts of a key.
+func (d decoder) mapping(part *unstable.Node) *yaml.Node {
+	line, column := d.position(part)
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: line, Column: column}
+}
+
+func (d decoder) keyParts(expr *unstable.Node) []*unstable.Node {
+	var parts []*unstable.Node
+	it := expr.Key()
+	for it.Next() {
+		parts = append(parts, it.Node())
+	}
+	return parts
+}
+
+func (d decoder) position(node *unstable.Node) (line int, column int) {
+	shape := d.parser.Shape(node.Raw)
+	return shape.Start.Line, shape.Start.Column
+}
+
+// valuePosition finds the position of the value of a `key = value`
+// expression, which is needed because the parser doesn't keep the
+// position of arrays.
+func (d decoder) valuePosition(expr *unstable.Node) (line int, column int) {
+	parts := d.keyParts(expr)
+	last := parts[len(parts)-1].Raw
+
+	data := d.parser.Data()
+	offset := int(last.Offset + last.Length)
+	offset += bytes.IndexByte(data[offset:], '=') + 1
+	for offset < len(data) && (data[offset] == ' ' || data[offset] == '\t') {
+		offset++
+	}
+
+	shape := d.parser.Shape(unstable.Range{Offset: uint32(offset)})
+	return shape.Start.Line, shape.Start.Column
+}
+
+func child(table *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(table.Content); i += 2 {
+		if table.Content[i].Value == key {
+			return table.Content[i], table.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// lastItem returns the last table of an array of tables, which is
+// where the keys of later headers, e.g. [fruits.color], are added.
+func lastItem(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.SequenceNode && len(node.Content) > 0 {
+		return node.Content[len(node.Content)-1]
+	}
+	return node
+}
+
+// lineComment returns the comment written after the expression on the same line.
+func lineComment(expr *unstable.Node) string {
+	next := expr.Next()
+	if next == nil || next.Kind != unstable.Comment {
+		return ""
+	}
+	return string(next.Data)
+}
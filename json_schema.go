@@ -0,0 +1,343 @@
+package kparse
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vingarcia/structi"
+	"gopkg.in/yaml.v3"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema describes a JSON Schema (draft 2020-12) document.
+//
+// Only the keywords kparse is able to derive from struct tags
+// are represented here.
+type Schema struct {
+	Schema string `json:"$schema,omitempty"`
+
+	Type        string          `json:"type,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Default     json.RawMessage `json:"default,omitempty"`
+
+	// WriteOnly and Secret are set on secret fields, Secret is a kparse
+	// extension asking validators to never print the values of the field.
+	WriteOnly bool `json:"writeOnly,omitempty"`
+	Secret    bool `json:"x-kparse-secret,omitempty"`
+
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+
+	Const            json.Number `json:"const,omitempty"`
+	Minimum          json.Number `json:"minimum,omitempty"`
+	Maximum          json.Number `json:"maximum,omitempty"`
+	ExclusiveMinimum json.Number `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum json.Number `json:"exclusiveMaximum,omitempty"`
+
+	MinLength     *int `json:"minLength,omitempty"`
+	MaxLength     *int `json:"maxLength,omitempty"`
+	MinItems      *int `json:"minItems,omitempty"`
+	MaxItems      *int `json:"maxItems,omitempty"`
+	MinProperties *int `json:"minProperties,omitempty"`
+	MaxProperties *int `json:"maxProperties,omitempty"`
+}
+
+// JSONSchema generates a JSON Schema describing the struct pointed by
+// structPtr, using the values of the `tagName` tag as the property names.
+//
+// The struct is walked the same way the Parse functions do, so required
+// fields, default values and the range and len validators all show up
+// on the resulting schema.
+func JSONSchema(structPtr any, tagName string) ([]byte, error) {
+	t := reflect.TypeOf(structPtr)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct pointer but got: %T", structPtr)
+	}
+
+	schema, err := structSchema(tagName, t.Elem())
+	if err != nil {
+		return nil, err
+	}
+	schema.Schema = jsonSchemaDraft
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func structSchema(tagName string, t reflect.Type) (*Schema, error) {
+	info, err := structi.GetStructInfo(t)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{},
+	}
+	for _, field := range info.Fields {
+		key := keyFromTag(field.Tags[tagName])
+		if key == "" {
+			continue
+		}
+
+		property, err := typeSchema(tagName, field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("error generating schema for field '%s': %w", field.Name, err)
+		}
+
+		property.Description = field.Tags["desc"]
+		secret := isSecretField(field.Type, field.Tags)
+		if secret {
+			property.WriteOnly = true
+			property.Secret = true
+		}
+
+		required, err := applyValidationsToSchema(property, field.Name, unwrapSecretType(field.Type), field.Tags["validate"])
+		if err != nil {
+			return nil, err
+		}
+
+		// The defaults of secrets are not published on the schema:
+		defaultYAML := field.Tags["default"]
+		if defaultYAML != "" && !secret {
+			property.Default, err = defaultAsJSON(unwrapSecretType(field.Type), defaultYAML)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing default value of field '%s': %w", field.Name, err)
+			}
+		}
+
+		// A missing required field is still valid if it has a default value:
+		if required && defaultYAML == "" {
+			schema.Required = append(schema.Required, key)
+		}
+
+		// Including the defaults of the fields of secret structs:
+		if secret {
+			omitDefaults(property)
+		}
+
+		schema.Properties[key] = property
+	}
+
+	return schema, nil
+}
+
+// omitDefaults removes the default values of the schema and its subschemas.
+func omitDefaults(schema *Schema) {
+	schema.Default = nil
+	for _, property := range schema.Properties {
+		omitDefaults(property)
+	}
+	if schema.Items != nil {
+		omitDefaults(schema.Items)
+	}
+	if schema.AdditionalProperties != nil {
+		omitDefaults(schema.AdditionalProperties)
+	}
+}
+
+func typeSchema(tagName string, t reflect.Type) (*Schema, error) {
+	if isSecretType(t) {
+		return typeSchema(tagName, unwrapSecretType(t))
+	}
+
+	if isTextType(t) {
+		return &Schema{Type: "string"}, nil
+	}
+
+	// The form of types decoding themselves from YAML is unknown, any value is accepted:
+	if reflect.PointerTo(t).Implements(yamlUnmarshalerType) {
+		return &Schema{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(tagName, t.Elem())
+	case reflect.Struct:
+		return structSchema(tagName, t)
+	case reflect.Slice, reflect.Array:
+		items, err := typeSchema(tagName, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		values, err := typeSchema(tagName, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.Interface:
+		// Any value is accepted:
+		return &Schema{}, nil
+	}
+
+	return nil, fmt.Errorf("type %v is not supported on JSON schemas", t)
+}
+
+// applyValidationsToSchema translates the expressions on a `validate` tag
+// into the equivalent JSON Schema keywords, it also reports if one of
+// the expressions was the `required` validation.
+func applyValidationsToSchema(schema *Schema, fieldName string, t reflect.Type, validateTag string) (required bool, _ error) {
+	if validateTag == "" {
+		return false, nil
+	}
+
+	for _, exp := range strings.Split(validateTag, ",") {
+		validatorName, rule := extractValidatorNameAndRule(exp)
+		if validatorName == "required" {
+			required = true
+			continue
+		}
+
+		if _, found := validatorFactoryMap[validatorFactoryMapKey{validatorName, t.Kind()}]; !found {
+			return false, fmt.Errorf(
+				"unrecognized validation exp: '%s' on struct field: '%s'",
+				exp, fieldName,
+			)
+		}
+
+		// Ranges of values written as text, e.g. durations, can't be described by the schema:
+		if isTextType(t) {
+			continue
+		}
+
+		op, limit := splitRule(rule)
+		switch validatorName {
+		case "":
+			err := applyRangeToSchema(schema, op, limit)
+			if err != nil {
+				return false, fmt.Errorf("invalid validation exp: '%s' on struct field: '%s': %w", exp, fieldName, err)
+			}
+		case "len":
+			err := applyLenToSchema(schema, t.Kind(), op, limit)
+			if err != nil {
+				return false, fmt.Errorf("invalid validation exp: '%s' on struct field: '%s': %w", exp, fieldName, err)
+			}
+		}
+	}
+
+	return required, nil
+}
+
+func applyRangeToSchema(schema *Schema, op string, limit string) error {
+	if _, err := strconv.ParseFloat(limit, 64); err != nil {
+		return fmt.Errorf("limit is not a number: '%s'", limit)
+	}
+
+	n := json.Number(limit)
+	switch op {
+	case "<":
+		schema.ExclusiveMaximum = n
+	case "<=":
+		schema.Maximum = n
+	case ">":
+		schema.ExclusiveMinimum = n
+	case ">=":
+		schema.Minimum = n
+	case "=":
+		schema.Const = n
+	default:
+		return fmt.Errorf("unrecognized operator: '%s'", op)
+	}
+
+	return nil
+}
+
+func applyLenToSchema(schema *Schema, kind reflect.Kind, op string, limit string) error {
+	n, err := strconv.Atoi(limit)
+	if err != nil {
+		return fmt.Errorf("limit is not an integer: '%s'", limit)
+	}
+
+	var minLen, maxLen *int
+	switch op {
+	case "<":
+		maxLen = intPtr(n - 1)
+	case "<=":
+		maxLen = intPtr(n)
+	case ">":
+		minLen = intPtr(n + 1)
+	case ">=":
+		minLen = intPtr(n)
+	case "=":
+		minLen, maxLen = intPtr(n), intPtr(n)
+	default:
+		return fmt.Errorf("unrecognized operator: '%s'", op)
+	}
+
+	switch kind {
+	case reflect.String:
+		schema.MinLength = coalesce(minLen, schema.MinLength)
+		schema.MaxLength = coalesce(maxLen, schema.MaxLength)
+	case reflect.Map:
+		schema.MinProperties = coalesce(minLen, schema.MinProperties)
+		schema.MaxProperties = coalesce(maxLen, schema.MaxProperties)
+	default:
+		schema.MinItems = coalesce(minLen, schema.MinItems)
+		schema.MaxItems = coalesce(maxLen, schema.MaxItems)
+	}
+
+	return nil
+}
+
+// defaultAsJSON parses the YAML on a `default` tag into the field type
+// and then encodes it as JSON, so the schema default has the right type.
+func defaultAsJSON(t reflect.Type, defaultYAML string) (json.RawMessage, error) {
+	value := reflect.New(t)
+	err := yaml.Unmarshal([]byte(defaultYAML), value.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	// Values written as text use their text form, e.g. "10s" instead of 10000000000:
+	if isTextType(t) {
+		if stringer, ok := value.Elem().Interface().(fmt.Stringer); ok {
+			return json.Marshal(stringer.String())
+		}
+		return json.Marshal(strings.TrimSpace(defaultYAML))
+	}
+
+	return json.Marshal(value.Elem().Interface())
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isTextType reports if the values of t are written as strings on
+// config files, e.g. "5s" for time.Duration, even if t is a number.
+func isTextType(t reflect.Type) bool {
+	return t == durationType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func coalesce(ptrs ...*int) *int {
+	for _, ptr := range ptrs {
+		if ptr != nil {
+			return ptr
+		}
+	}
+	return nil
+}
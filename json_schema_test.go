@@ -0,0 +1,313 @@
+package kparse
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+	"gopkg.in/yaml.v3"
+)
+
+func TestJSONSchema(t *testing.T) {
+	tests := []struct {
+		desc               string
+		structPtr          any
+		expectedSchema     map[string]any
+		expectErrToContain []string
+	}{
+		{
+			desc: "should map basic types to properties",
+			structPtr: &struct {
+				Name    string         `yaml:"name"`
+				Age     int            `yaml:"age"`
+				Height  float64        `yaml:"height"`
+				Active  bool           `yaml:"active"`
+				Any     any            `yaml:"any"`
+				Ignored string         `json:"ignored"`
+				Skipped string         `yaml:"-"`
+				Labels  map[string]int `yaml:"labels"`
+			}{},
+			expectedSchema: map[string]any{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type":    "object",
+				"properties": map[string]any{
+					"name":   map[string]any{"type": "string"},
+					"age":    map[string]any{"type": "integer"},
+					"height": map[string]any{"type": "number"},
+					"active": map[string]any{"type": "boolean"},
+					"any":    map[string]any{},
+					"labels": map[string]any{
+						"type":                 "object",
+						"additionalProperties": map[string]any{"type": "integer"},
+					},
+				},
+			},
+		},
+		{
+			desc: "should describe required fields and defaults",
+			structPtr: &struct {
+				SecretKey int    `yaml:"secretKey" validate:"required"`
+				BaseURL   string `yaml:"baseUrl" default:"https://example.com"`
+				Retries   int    `yaml:"retries" validate:"required" default:"3"`
+			}{},
+			expectedSchema: map[string]any{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type":    "object",
+				"properties": map[string]any{
+					"secretKey": map[string]any{"type": "integer"},
+					"baseUrl":   map[string]any{"type": "string", "default": "https://example.com"},
+					"retries":   map[string]any{"type": "integer", "default": float64(3)},
+				},
+				"required": []any{"secretKey"},
+			},
+		},
+		{
+			desc: "should describe types written as text as strings",
+			structPtr: &struct {
+				Timeout  time.Duration `yaml:"timeout" default:"10s" validate:">0"`
+				Interval time.Duration `yaml:"interval"`
+				IP       net.IP        `yaml:"ip" default:"127.0.0.1"`
+			}{},
+			expectedSchema: map[string]any{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type":    "object",
+				"properties": map[string]any{
+					"timeout":  map[string]any{"type": "string", "default": "10s"},
+					"interval": map[string]any{"type": "string"},
+					"ip":       map[string]any{"type": "string", "default": "127.0.0.1"},
+				},
+			},
+		},
+		{
+			desc: "should translate range and len validators",
+			structPtr: &struct {
+				MaxRetries int               `yaml:"maxRetries" validate:">0,<=10"`
+				Ratio      float64           `yaml:"ratio" validate:">=0.5,<1.5"`
+				Exact      int               `yaml:"exact" validate:"=3"`
+				Desc       string            `yaml:"desc" validate:"len>=30"`
+				Domains    []string          `yaml:"domains" validate:"len>0,len<5"`
+				Tags       map[string]string `yaml:"tags" validate:"len=2"`
+			}{},
+			expectedSchema: map[string]any{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type":    "object",
+				"properties": map[string]any{
+					"maxRetries": map[string]any{"type": "integer", "exclusiveMinimum": float64(0), "maximum": float64(10)},
+					"ratio":      map[string]any{"type": "number", "minimum": 0.5, "exclusiveMaximum": 1.5},
+					"exact":      map[string]any{"type": "integer", "const": float64(3)},
+					"desc":       map[string]any{"type": "string", "minLength": float64(30)},
+					"domains": map[string]any{
+						"type":     "array",
+						"items":    map[string]any{"type": "string"},
+						"minItems": float64(1),
+						"maxItems": float64(4),
+					},
+					"tags": map[string]any{
+						"type":                 "object",
+						"additionalProperties": map[string]any{"type": "string"},
+						"minProperties":        float64(2),
+						"maxProperties":        float64(2),
+					},
+				},
+			},
+		},
+		{
+			desc: "should describe nested structs and slices of structs",
+			structPtr: &struct {
+				Address struct {
+					Street string `yaml:"street" default:"defaultStreet"`
+					City   string `yaml:"city" validate:"required"`
+				} `yaml:"address"`
+				Users []struct {
+					Name string `yaml:"name" validate:"required"`
+				} `yaml:"users"`
+			}{},
+			expectedSchema: map[string]any{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type":    "object",
+				"properties": map[string]any{
+					"address": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"street": map[string]any{"type": "string", "default": "defaultStreet"},
+							"city":   map[string]any{"type": "string"},
+						},
+						"required": []any{"city"},
+					},
+					"users": map[string]any{
+						"type": "array",
+						"items": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"name": map[string]any{"type": "string"},
+							},
+							"required": []any{"name"},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "should use the desc tag as the description",
+			structPtr: &struct {
+				Name string `yaml:"name" desc:"The name of the service"`
+			}{},
+			expectedSchema: map[string]any{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type":    "object",
+				"properties": map[string]any{
+					"name": map[string]any{"type": "string", "description": "The name of the service"},
+				},
+			},
+		},
+		{
+			desc: "should describe secrets by the type they wrap",
+			structPtr: &struct {
+				Password Secret[string] `yaml:"password" validate:"required,len>=8"`
+			}{},
+			expectedSchema: map[string]any{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type":    "object",
+				"properties": map[string]any{
+					"password": map[string]any{
+						"type":            "string",
+						"minLength":       float64(8),
+						"writeOnly":       true,
+						"x-kparse-secret": true,
+					},
+				},
+				"required": []any{"password"},
+			},
+		},
+		{
+			desc: "should mark fields with the secret tag as secrets",
+			structPtr: &struct {
+				Pin int `yaml:"pin" secret:"true" validate:"<1000"`
+			}{},
+			expectedSchema: map[string]any{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type":    "object",
+				"properties": map[string]any{
+					"pin": map[string]any{
+						"type":             "integer",
+						"exclusiveMaximum": float64(1000),
+						"writeOnly":        true,
+						"x-kparse-secret":  true,
+					},
+				},
+			},
+		},
+		{
+			desc: "should leave out the defaults of secrets",
+			structPtr: &struct {
+				Token string `yaml:"token" secret:"true" default:"hunter2"`
+				Auth  struct {
+					Pin int `yaml:"pin" default:"4242"`
+				} `yaml:"auth" secret:"true"`
+			}{},
+			expectedSchema: map[string]any{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type":    "object",
+				"properties": map[string]any{
+					"token": map[string]any{
+						"type":            "string",
+						"writeOnly":       true,
+						"x-kparse-secret": true,
+					},
+					"auth": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"pin": map[string]any{"type": "integer"},
+						},
+						"writeOnly":       true,
+						"x-kparse-secret": true,
+					},
+				},
+			},
+		},
+		{
+			desc: "should describe the values of maps of structs",
+			structPtr: &struct {
+				Backends map[string]*struct {
+					URL string `yaml:"url" validate:"required"`
+				} `yaml:"backends"`
+			}{},
+			expectedSchema: map[string]any{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type":    "object",
+				"properties": map[string]any{
+					"backends": map[string]any{
+						"type": "object",
+						"additionalProperties": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"url": map[string]any{"type": "string"},
+							},
+							"required": []any{"url"},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "should accept any value for types with their own yaml unmarshalers",
+			structPtr: &struct {
+				Endpoint schemaEndpoint `yaml:"endpoint"`
+				StartAt  time.Time      `yaml:"startAt"`
+			}{},
+			expectedSchema: map[string]any{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type":    "object",
+				"properties": map[string]any{
+					"endpoint": map[string]any{},
+					"startAt":  map[string]any{"type": "string"},
+				},
+			},
+		},
+		{
+			desc: "should report unrecognized validations",
+			structPtr: &struct {
+				Name string `yaml:"name" validate:"not_required"`
+			}{},
+			expectErrToContain: []string{"validation", "not_required", "Name"},
+		},
+		{
+			desc:               "should report non struct pointers",
+			structPtr:          struct{}{},
+			expectErrToContain: []string{"expected struct pointer"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			rawSchema, err := JSONSchema(test.structPtr, "yaml")
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+
+			var schema map[string]any
+			err = json.Unmarshal(rawSchema, &schema)
+			tt.AssertNoErr(t, err)
+
+			tt.AssertEqual(t, schema, test.expectedSchema)
+		})
+	}
+}
+
+// schemaEndpoint is decoded from either a mapping or a plain URL.
+type schemaEndpoint struct {
+	URL string `yaml:"url"`
+}
+
+func (e *schemaEndpoint) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&e.URL)
+	}
+
+	type plain schemaEndpoint
+	return node.Decode((*plain)(e))
+}
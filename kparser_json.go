@@ -9,14 +9,44 @@ import (
 	"path/filepath"
 )
 
-func MustParseJSONFile(filepath string, targetStruct any) {
-	err := ParseJSONFile(filepath, targetStruct)
+func MustParseJSONFile(filepath string, targetStruct any, opts ...Option) {
+	err := ParseJSONFile(filepath, targetStruct, opts...)
 	if err != nil {
 		panic(err)
 	}
 }
 
-func ParseJSONFile(path string, targetStruct any) (err error) {
+func ParseJSONFile(path string, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseJSONFile(path, targetStruct, opts...)
+}
+
+func MustParseJSON(file []byte, targetStruct any, opts ...Option) {
+	err := ParseJSON(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func ParseJSON(file []byte, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseJSON(file, targetStruct, opts...)
+}
+
+func MustParseJSONFromReader(file io.Reader, targetStruct any, opts ...Option) {
+	err := ParseJSONFromReader(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func ParseJSONFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseJSONFromReader(file, targetStruct, opts...)
+}
+
+// ParseJSONFile works like the top-level ParseJSONFile using the settings of the Parser.
+func (p *Parser) ParseJSONFile(path string, targetStruct any, opts ...Option) (err error) {
+	// The path is used as the source name unless the caller set another one:
+	opts = append([]Option{WithSourceName(path)}, opts...)
+
 	if !filepath.IsAbs(path) {
 		workingDir, err := os.Getwd()
 		if err != nil {
@@ -33,33 +63,21 @@ func ParseJSONFile(path string, targetStruct any) (err error) {
 		err = errors.Join(err, file.Close())
 	}()
 
-	return ParseJSONFromReader(file, targetStruct)
+	return p.ParseJSONFromReader(file, targetStruct, opts...)
 }
 
-func MustParseJSON(file []byte, targetStruct any) {
-	err := ParseJSON(file, targetStruct)
-	if err != nil {
-		panic(err)
-	}
-}
-
-func ParseJSON(file []byte, targetStruct any) error {
-	return ParseJSONFromReader(bytes.NewReader(file), targetStruct)
-}
-
-func MustParseJSONFromReader(file io.Reader, targetStruct any) {
-	err := ParseJSONFromReader(file, targetStruct)
-	if err != nil {
-		panic(err)
-	}
+// ParseJSON works like the top-level ParseJSON using the settings of the Parser.
+func (p *Parser) ParseJSON(file []byte, targetStruct any, opts ...Option) error {
+	return p.ParseJSONFromReader(bytes.NewReader(file), targetStruct, opts...)
 }
 
-func ParseJSONFromReader(file io.Reader, targetStruct any) error {
-	var data map[string]LazyDecoder
+// ParseJSONFromReader works like the top-level ParseJSONFromReader using the settings of the Parser.
+func (p *Parser) ParseJSONFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	var data LazyDecoder
 	err := json.NewDecoder(file).Decode(&data)
 	if err != nil {
 		return err
 	}
 
-	return parseFromMap("json", targetStruct, data)
+	return p.newParseState("json", opts).parseRoot(targetStruct, data)
 }
@@ -0,0 +1,329 @@
+package kparse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+func MustParseJSONCFile(path string, targetStruct any, opts ...Option) {
+	err := ParseJSONCFile(path, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ParseJSONCFile parses a JSON file with comments, see ParseJSONC.
+func ParseJSONCFile(path string, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseJSONCFile(path, targetStruct, opts...)
+}
+
+func MustParseJSONC(file []byte, targetStruct any, opts ...Option) {
+	err := ParseJSONC(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ParseJSONC works like ParseJSON but also accepts `//` and `/* */`
+// comments and trailing commas, which are common on hand-written files:
+//
+//	{
+//		// Retries before giving up:
+//		"maxRetries": 5,
+//		"domains": ["a.com", "b.com",],
+//	}
+//
+// Syntax errors, values of the wrong type and validation errors
+// are reported with the line and column of the file.
+func ParseJSONC(file []byte, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseJSONC(file, targetStruct, opts...)
+}
+
+func MustParseJSONCFromReader(file io.Reader, targetStruct any, opts ...Option) {
+	err := ParseJSONCFromReader(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func ParseJSONCFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseJSONCFromReader(file, targetStruct, opts...)
+}
+
+// ParseJSONCFile works like the top-level ParseJSONCFile using the settings of the Parser.
+func (p *Parser) ParseJSONCFile(path string, targetStruct any, opts ...Option) (err error) {
+	// The path is used as the source name unless the caller set another one:
+	opts = append([]Option{WithSourceName(path)}, opts...)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, file.Close())
+	}()
+
+	return p.ParseJSONCFromReader(file, targetStruct, opts...)
+}
+
+// ParseJSONC works like the top-level ParseJSONC using the settings of the Parser.
+func (p *Parser) ParseJSONC(file []byte, targetStruct any, opts ...Option) error {
+	return p.ParseJSONCFromReader(bytes.NewReader(file), targetStruct, opts...)
+}
+
+// ParseJSONCFromReader works like the top-level ParseJSONCFromReader using the settings of the Parser.
+func (p *Parser) ParseJSONCFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	state := p.newParseState("json", opts)
+
+	content, err = stripJSONC(content)
+	if err != nil {
+		return withJSONPosition(state.options.sourceName, content, err)
+	}
+
+	// The values are decoded from their offsets on the file,
+	// so their errors can report where they were written:
+	var raw json.RawMessage
+	err = json.Unmarshal(content, &raw)
+	if err != nil {
+		return withJSONPosition(state.options.sourceName, content, err)
+	}
+
+	state.validationPositions = true
+	return state.parseRoot(targetStruct, jsoncDecoder(state.options.sourceName, content, skipJSONSpaces(content, 0)))
+}
+
+// jsoncDecoder returns a LazyDecoder for the JSON value starting at
+// the offset of a valid JSON file. Objects and arrays are decoded item
+// by item, so the errors of each item report the item position.
+func jsoncDecoder(source string, file []byte, offset int) LazyDecoder {
+	return func(target any) error {
+		switch target := target.(type) {
+		case *originProbe:
+			target.line, target.column = jsonPosition(file, offset)
+			return nil
+
+		case *map[string]LazyDecoder:
+			if file[offset] == '{' {
+				*target = map[string]LazyDecoder{}
+				return jsonItems(file, offset, func(key string, itemOffset int) {
+					(*target)[key] = jsoncDecoder(source, file, itemOffset)
+				})
+			}
+
+		case *[]LazyDecoder:
+			if file[offset] == '[' {
+				*target = []LazyDecoder{}
+				return jsonItems(file, offset, func(_ string, itemOffset int) {
+					*target = append(*target, jsoncDecoder(source, file, itemOffset))
+				})
+			}
+		}
+
+		// Lists are also decoded item by item, e.g. for []string fields:
+		v := reflect.ValueOf(target)
+		_, isUnmarshaler := target.(json.Unmarshaler)
+		if file[offset] == '[' && !isUnmarshaler && v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Slice {
+			var items []LazyDecoder
+			err := jsoncDecoder(source, file, offset).Decode(&items)
+			if err != nil {
+				return err
+			}
+
+			slice := reflect.MakeSlice(v.Elem().Type(), len(items), len(items))
+			for i, item := range items {
+				err := item.Decode(slice.Index(i).Addr().Interface())
+				if err != nil {
+					return err
+				}
+			}
+			v.Elem().Set(slice)
+			return nil
+		}
+
+		err := json.NewDecoder(bytes.NewReader(file[offset:])).Decode(target)
+		if err != nil {
+			line, column := jsonPosition(file, offset)
+			return positionError(source, line, column, err)
+		}
+
+		return nil
+	}
+}
+
+// jsonItems calls fn with the key and offset of each item of the object or
+// array starting at the offset, the keys of array items are always empty.
+func jsonItems(file []byte, offset int, fn func(key string, itemOffset int)) error {
+	decoder := json.NewDecoder(bytes.NewReader(file[offset:]))
+
+	// Reads the opening '{' or '[':
+	_, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	isObject := file[offset] == '{'
+	for decoder.More() {
+		var key string
+		if isObject {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key, _ = token.(string)
+		}
+
+		// The decoder offset stops before the separators of the item:
+		itemOffset := offset + int(decoder.InputOffset())
+		itemOffset = skipJSONSpaces(file, itemOffset)
+		if file[itemOffset] == ':' || file[itemOffset] == ',' {
+			itemOffset = skipJSONSpaces(file, itemOffset+1)
+		}
+
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if err != nil {
+			return err
+		}
+
+		fn(key, itemOffset)
+	}
+
+	return nil
+}
+
+func skipJSONSpaces(file []byte, offset int) int {
+	for offset < len(file) && strings.IndexByte(" \t\r\n", file[offset]) != -1 {
+		offset++
+	}
+	return offset
+}
+
+// jsoncError is returned for invalid comments, its offset
+// works like the offset of json.SyntaxError.
+type jsoncError struct {
+	msg    string
+	offset int64
+}
+
+func (e *jsoncError) Error() string {
+	return e.msg
+}
+
+// stripJSONC replaces the comments and trailing commas of the file by
+// spaces, keeping the line breaks, so the offsets of the result are
+// the same of the original file.
+func stripJSONC(file []byte) ([]byte, error) {
+	out := bytes.Clone(file)
+
+	inString := false
+	lastComma := -1
+	var previous byte
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			lastComma = -1
+			previous = c
+
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			for i < len(out) && out[i] != '\n' {
+				out[i] = ' '
+				i++
+			}
+
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			start := i
+			end := bytes.Index(out[i+2:], []byte("*/"))
+			if end == -1 {
+				return out, &jsoncError{msg: "unterminated comment", offset: int64(start + 1)}
+			}
+
+			for ; i < start+2+end+2; i++ {
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+			i--
+
+		case c == ',':
+			// Commas without a value before them are left for the JSON parser to report:
+			lastComma = -1
+			if previous != 0 && previous != '[' && previous != '{' && previous != ',' {
+				lastComma = i
+			}
+			previous = c
+
+		case c == '}' || c == ']':
+			if lastComma != -1 {
+				out[lastComma] = ' '
+			}
+			lastComma = -1
+			previous = c
+
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			lastComma = -1
+			previous = c
+		}
+	}
+
+	return out, nil
+}
+
+// withJSONPosition adds the line and column of syntax errors to the error.
+func withJSONPosition(source string, file []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var jsoncErr *jsoncError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &jsoncErr):
+		offset = jsoncErr.offset
+	default:
+		return err
+	}
+
+	// The offset counts the bytes read, including the invalid one:
+	line, column := jsonPosition(file, int(offset)-1)
+	return positionError(source, line, column, err)
+}
+
+// jsonPosition returns the line and column of a byte offset of the file.
+func jsonPosition(file []byte, offset int) (line int, column int) {
+	pos := min(max(offset, 0), len(file))
+	line = bytes.Count(file[:pos], []byte("\n")) + 1
+	column = pos - bytes.LastIndexByte(file[:pos], '\n')
+	return line, column
+}
+
+// positionError adds the position of the error on a source file to the error,
+// e.g. "config.jsonc:3:5: ..." or "line 3, column 5: ..." if there is no source name.
+func positionError(source string, line int, column int, err error) error {
+	if source != "" {
+		return fmt.Errorf("%s:%d:%d: %w", source, line, column, err)
+	}
+	return fmt.Errorf("line %d, column %d: %w", line, column, err)
+}
@@ -0,0 +1,129 @@
+package kparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestParseJSONC(t *testing.T) {
+	type Config struct {
+		MaxRetries int      `json:"maxRetries" default:"3" validate:"<=10"`
+		Domains    []string `json:"domains"`
+		Comment    string   `json:"comment"`
+		Database   struct {
+			Host string `json:"host" validate:"required"`
+		} `json:"database"`
+	}
+
+	tests := []struct {
+		desc               string
+		file               string
+		expectedConfig     Config
+		expectErrToContain []string
+	}{
+		{
+			desc: "should ignore comments and trailing commas",
+			file: `// Hand-written config
+{
+	/* Retries before
+	   giving up */
+	"maxRetries": 5, // inline comment
+	"domains": ["a.com", "b.com", /* trailing */ ],
+	"comment": "// not a comment, /* nor this */ \" ,]",
+	"database": {"host": "localhost",},
+}`,
+			expectedConfig: Config{
+				MaxRetries: 5,
+				Domains:    []string{"a.com", "b.com"},
+				Comment:    `// not a comment, /* nor this */ " ,]`,
+				Database: struct {
+					Host string `json:"host" validate:"required"`
+				}{Host: "localhost"},
+			},
+		},
+		{
+			desc:               "should apply the defaults and validations",
+			file:               "{\n\t// no database\n\t\"maxRetries\": 50,\n}",
+			expectErrToContain: []string{"missing required field 'host'", "MaxRetries"},
+		},
+		{
+			desc: "should report the line and column of syntax errors",
+			file: `{
+	// comment
+	"maxRetries": 5,
+	"domains": ["a.com" "b.com"]
+}`,
+			expectErrToContain: []string{"line 4, column 22: invalid character '\"' after array element"},
+		},
+		{
+			desc:               "should report unterminated comments",
+			file:               "{\n\t\"maxRetries\": 5 /* comment\n}",
+			expectErrToContain: []string{"line 2, column 18: unterminated comment"},
+		},
+		{
+			desc:               "should not accept commas without values",
+			file:               "{\"domains\": [,]}",
+			expectErrToContain: []string{"line 1, column 14: invalid character ','"},
+		},
+		{
+			desc:               "should report the line and column of values of the wrong type",
+			file:               "{\n\t// retries\n\t\"maxRetries\": \"abc\",\n}",
+			expectErrToContain: []string{"line 3, column 16: json: cannot unmarshal string"},
+		},
+		{
+			desc:               "should report the line and column of list items of the wrong type",
+			file:               "{\n\t\"domains\": [\n\t\t\"a.com\", // first\n\t\t5,\n\t],\n}",
+			expectErrToContain: []string{"line 4, column 3: json: cannot unmarshal number"},
+		},
+		{
+			desc:               "should report the line and column of validation errors",
+			file:               "{\n\t\"maxRetries\": 50,\n\t\"database\": {\"host\": \"localhost\"}\n}",
+			expectErrToContain: []string{"line 2, column 16:", "MaxRetries", "<= 10"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var config Config
+			err := ParseJSONC([]byte(test.file), &config)
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, config, test.expectedConfig)
+		})
+	}
+
+	t.Run("should report the file path on syntax errors", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.jsonc")
+		err := os.WriteFile(path, []byte("{\n  \"maxRetries\": 5,,\n}"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config Config
+		err = ParseJSONCFile(path, &config)
+		tt.AssertErrContains(t, err, path+":2:19: invalid character ','")
+	})
+
+	t.Run("should report the file path on type errors", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.jsonc")
+		err := os.WriteFile(path, []byte("{\n  \"database\": {\n    \"host\": 42\n  }\n}"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config Config
+		err = ParseJSONCFile(path, &config)
+		tt.AssertErrContains(t, err, path+":3:13: json: cannot unmarshal number")
+	})
+
+	t.Run("should record the positions of the values on the origins", func(t *testing.T) {
+		var config Config
+		var origins Origins
+		err := ParseJSONC([]byte("{\n  // retries\n  \"maxRetries\": 5,\n  \"database\": {\"host\": \"localhost\"}\n}"), &config, WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, origins["maxRetries"], Origin{Line: 3, Column: 17})
+		tt.AssertEqual(t, origins["database.host"], Origin{Line: 4, Column: 24})
+	})
+}
@@ -3,21 +3,53 @@ package kparse
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"iter"
 	"os"
 	"path/filepath"
 
 	"gopkg.in/yaml.v3"
 )
 
-func MustParseYAMLFile(filepath string, targetStruct any) {
-	err := ParseYAMLFile(filepath, targetStruct)
+func MustParseYAMLFile(filepath string, targetStruct any, opts ...Option) {
+	err := ParseYAMLFile(filepath, targetStruct, opts...)
 	if err != nil {
 		panic(err)
 	}
 }
 
-func ParseYAMLFile(path string, targetStruct any) (err error) {
+func ParseYAMLFile(path string, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseYAMLFile(path, targetStruct, opts...)
+}
+
+func MustParseYAML(file []byte, targetStruct any, opts ...Option) {
+	err := ParseYAML(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func ParseYAML(file []byte, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseYAML(file, targetStruct, opts...)
+}
+
+func MustParseYAMLFromReader(file io.Reader, targetStruct any, opts ...Option) {
+	err := ParseYAMLFromReader(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func ParseYAMLFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseYAMLFromReader(file, targetStruct, opts...)
+}
+
+// ParseYAMLFile works like the top-level ParseYAMLFile using the settings of the Parser.
+func (p *Parser) ParseYAMLFile(path string, targetStruct any, opts ...Option) (err error) {
+	// The path is used as the source name unless the caller set another one:
+	opts = append([]Option{WithSourceName(path)}, opts...)
+
 	if !filepath.IsAbs(path) {
 		workingDir, err := os.Getwd()
 		if err != nil {
@@ -34,33 +66,92 @@ func ParseYAMLFile(path string, targetStruct any) (err error) {
 		err = errors.Join(err, file.Close())
 	}()
 
-	return ParseYAMLFromReader(file, targetStruct)
+	return p.ParseYAMLFromReader(file, targetStruct, opts...)
 }
 
-func MustParseYAML(file []byte, targetStruct any) {
-	err := ParseYAML(file, targetStruct)
+// ParseYAML works like the top-level ParseYAML using the settings of the Parser.
+func (p *Parser) ParseYAML(file []byte, targetStruct any, opts ...Option) error {
+	return p.ParseYAMLFromReader(bytes.NewReader(file), targetStruct, opts...)
+}
+
+// ParseYAMLFromReader works like the top-level ParseYAMLFromReader using the settings of the Parser.
+func (p *Parser) ParseYAMLFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	var data LazyDecoder
+	err := yaml.NewDecoder(file).Decode(&data)
 	if err != nil {
-		panic(err)
+		return err
 	}
-}
 
-func ParseYAML(file []byte, targetStruct any) error {
-	return ParseYAMLFromReader(bytes.NewReader(file), targetStruct)
+	return p.newParseState("yaml", opts).parseRoot(targetStruct, data)
 }
 
-func MustParseYAMLFromReader(file io.Reader, targetStruct any) {
-	err := ParseYAMLFromReader(file, targetStruct)
+// ParseYAMLDocumentsFile works like ParseYAMLDocuments
+// reading the documents from the given file.
+func ParseYAMLDocumentsFile[T any](path string, opts ...Option) (_ []T, err error) {
+	opts = append([]Option{WithSourceName(path)}, opts...)
+
+	file, err := os.Open(path)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	defer func() {
+		err = errors.Join(err, file.Close())
+	}()
+
+	return ParseYAMLDocuments[T](file, opts...)
 }
 
-func ParseYAMLFromReader(file io.Reader, targetStruct any) error {
-	var data map[string]LazyDecoder
-	err := yaml.NewDecoder(file).Decode(&data)
-	if err != nil {
-		return err
+// ParseYAMLDocuments parses a multi-document YAML stream, where the
+// documents are separated by `---`, returning one value per document.
+//
+// All documents are parsed and validated even if some of them fail, and
+// the errors are prefixed with the index of the document, starting at 0.
+func ParseYAMLDocuments[T any](file io.Reader, opts ...Option) ([]T, error) {
+	var results []T
+	var errs error
+	for value, err := range YAMLDocuments[T](file, opts...) {
+		results = append(results, value)
+		errs = errors.Join(errs, err)
 	}
+	if errs != nil {
+		return nil, errs
+	}
+
+	return results, nil
+}
+
+// YAMLDocuments iterates over the documents of a multi-document
+// YAML stream, parsing each of them into a new value of type T, e.g.:
+//
+//	for tenant, err := range kparse.YAMLDocuments[TenantConfig](file) {
+//		...
+//	}
+//
+// Validation errors don't stop the iteration, but syntax errors do,
+// since the following documents can't be read after them.
+func YAMLDocuments[T any](file io.Reader, opts ...Option) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		decoder := yaml.NewDecoder(file)
+		for i := 0; ; i++ {
+			var value T
+			var data LazyDecoder
+			err := decoder.Decode(&data)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(value, fmt.Errorf("document %d: %w", i, err))
+				return
+			}
+
+			err = defaultParser.newParseState("yaml", opts).parseRoot(&value, data)
+			if err != nil {
+				err = fmt.Errorf("document %d: %w", i, err)
+			}
 
-	return parseFromMap("yaml", targetStruct, data)
+			if !yield(value, err) {
+				return
+			}
+		}
+	}
 }
@@ -1,6 +1,7 @@
 package kparse
 
 import (
+	"strings"
 	"testing"
 
 	tt "github.com/teamcollab-net/kparse/internal/testtools"
@@ -156,3 +157,84 @@ func TestParseYAMLReader(t *testing.T) {
 		})
 	}
 }
+
+func TestParseYAMLDocuments(t *testing.T) {
+	type Tenant struct {
+		Name     string `yaml:"name" validate:"required"`
+		MaxUsers int    `yaml:"maxUsers" default:"10" validate:"<=100"`
+	}
+
+	tests := []struct {
+		desc               string
+		input              string
+		expectedTenants    []Tenant
+		expectErrToContain []string
+	}{
+		{
+			desc: "should parse each document into a struct",
+			input: `name: fakeTenant1
+---
+name: fakeTenant2
+maxUsers: 50
+`,
+			expectedTenants: []Tenant{
+				{Name: "fakeTenant1", MaxUsers: 10},
+				{Name: "fakeTenant2", MaxUsers: 50},
+			},
+		},
+		{
+			desc: "should accept a leading document separator",
+			input: `---
+name: fakeTenant1
+`,
+			expectedTenants: []Tenant{
+				{Name: "fakeTenant1", MaxUsers: 10},
+			},
+		},
+		{
+			desc: "should report the errors of all documents with their indexes",
+			input: `name: fakeTenant1
+maxUsers: 200
+---
+name: fakeTenant2
+---
+maxUsers: 50
+`,
+			expectErrToContain: []string{"document 0", "MaxUsers", "document 2", "missing required field 'name'"},
+		},
+		{
+			desc: "should report syntax errors with the document index",
+			input: `name: fakeTenant1
+---
+name: [fakeTenant2
+`,
+			expectErrToContain: []string{"document 1", "yaml"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			tenants, err := ParseYAMLDocuments[Tenant](strings.NewReader(test.input))
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, tenants, test.expectedTenants)
+		})
+	}
+
+	t.Run("should stop iterating when the loop breaks", func(t *testing.T) {
+		input := "name: fakeTenant1\n---\nname: fakeTenant2\n---\nname: fakeTenant3\n"
+
+		var names []string
+		for tenant, err := range YAMLDocuments[Tenant](strings.NewReader(input)) {
+			tt.AssertNoErr(t, err)
+			names = append(names, tenant.Name)
+			if len(names) == 2 {
+				break
+			}
+		}
+		tt.AssertEqual(t, names, []string{"fakeTenant1", "fakeTenant2"})
+	})
+}
@@ -37,3 +37,23 @@ func (l *LazyDecoder) UnmarshalYAML(value *yaml.Node) error {
 
 	return nil
 }
+
+// scalarDecoder returns a LazyDecoder for values that are only available
+// as strings, e.g. contents of files or environment variables.
+//
+// The string is interpreted as a YAML scalar so it can also be decoded
+// into numbers and booleans, but never as a YAML document, so values
+// such as "foo: bar" are still decoded as strings.
+func scalarDecoder(value string) LazyDecoder {
+	return func(target any) error {
+		node := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+
+		var null any
+		if node.Decode(&null) == nil && null == nil {
+			// Otherwise strings such as "null" or "~" would be decoded as "":
+			node.Tag = "!!str"
+		}
+
+		return node.Decode(target)
+	}
+}
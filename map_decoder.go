@@ -1,9 +1,12 @@
 package kparse
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"io/fs"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 
@@ -16,19 +19,124 @@ type Validator func(value any) error
 // parseFromMap can be used to fill a struct with the values of a map.
 //
 // It works recursively so you can pass nested structs to it.
-func parseFromMap(tagName string, structPtr any, sourceMap map[string]LazyDecoder) (errs error) {
+func parseFromMap(tagName string, structPtr any, sourceMap map[string]LazyDecoder, opts ...Option) error {
+	state := defaultParser.newParseState(tagName, opts)
+	return state.parseStruct("", structPtr, sourceMap)
+}
+
+// parseState holds the settings and results of a single parse,
+// which are shared by all the recursive calls of parseStruct.
+type parseState struct {
+	options parseOptions
+	format  string
+
+	tagName     string
+	validateTag string
+	defaultTag  string
+
+	cache *sync.Map
+
+	// origins is only set if the caller asked for them to be recorded
+	origins Origins
+
+	// includesResolved is set when the includes of each source were already
+	// resolved relative to their own files, e.g. by Parser.Load
+	includesResolved bool
+
+	// validationPositions is set by the parsers whose values know their
+	// positions, so validation errors also report the line and column
+	validationPositions bool
+
+	// insideSecret is set while parsing the fields of a secret struct,
+	// whose fields are all treated as secrets
+	insideSecret bool
+}
+
+// parseRoot works like parseStruct but also accepts pointers to slices,
+// arrays and maps, parsing each of their elements recursively.
+func (s *parseState) parseRoot(targetPtr any, value LazyDecoder) error {
+	v := reflect.ValueOf(targetPtr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("expected non-nil pointer but got: %T", targetPtr)
+	}
+
+	if s.options.includes && !s.includesResolved {
+		var err error
+		value, err = s.resolveIncludes(value, s.options.sourceName, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	if value == nil {
+		// Empty documents are parsed as empty maps, so default values still apply:
+		value = func(target any) error { return nil }
+	}
+
+	var migrated *yaml.Node
+	if s.options.migrations != nil {
+		var err error
+		migrated, err = s.migrate(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	if migrated != nil {
+		value = func(target any) error {
+			return migrated.Decode(target)
+		}
+	}
+
+	err := s.parseValue("", v.Elem(), value)
+	if err != nil {
+		return err
+	}
+
+	// The migrated file is only written if it is valid:
+	if migrated != nil && s.options.migratedFilePath != "" {
+		return s.writeMigratedFile(migrated)
+	}
+
+	return nil
+}
+
+func (s *parseState) recordOrigin(path string, origin Origin) {
+	if s.origins != nil {
+		s.origins[path] = origin
+	}
+}
+
+// parseStruct fills the struct pointed by structPtr with the values of
+// sourceMap, path is the key path of the struct, e.g. "address" or
+// "users[0]", and is empty for the root struct.
+func (s *parseState) parseStruct(path string, structPtr any, sourceMap map[string]LazyDecoder) (errs error) {
+	usedKeys := map[string]bool{}
 	err := structi.ForEach(structPtr, func(field structi.Field) error {
-		// Ignore multiples fields if there is a `,` as in `json:"foo,omitempty"`
-		key := strings.SplitN(field.Tags[tagName], ",", 2)[0]
+		key := s.fieldKey(field)
 		if key == "" {
 			return nil
 		}
+		fieldPath := joinKeyPath(path, key)
 
 		required := false
+		secret := s.insideSecret || isSecretField(field.Type, field.Tags)
+		secretType := isSecretType(field.Type)
+		if secret && !s.insideSecret {
+			s.insideSecret = true
+			defer func() { s.insideSecret = false }()
+		}
+
+		// Structs with their own unmarshalers, e.g. time.Time, are decoded as a single value:
+		nestedStruct := field.Kind == reflect.Struct && containsStructs(field.Type)
+
+		// Secret types are validated by the type they wrap:
+		validationType := unwrapSecretType(field.Type)
 
 		validations := []Validator{}
-		if field.Tags["validate"] != "" {
-			expressions := strings.Split(field.Tags["validate"], ",")
+		validationExps := []string{}
+		if field.Tags[s.validateTag] != "" {
+			expressions := strings.Split(field.Tags[s.validateTag], ",")
 			for _, exp := range expressions {
 				validatorName, rule := extractValidatorNameAndRule(exp)
 
@@ -38,13 +146,14 @@ func parseFromMap(tagName string, structPtr any, sourceMap map[string]LazyDecode
 				}
 
 				cacheKey := cacheKey{
-					Kind:       field.Type.Kind(),
+					Kind:       validationType.Kind(),
 					FieldName:  field.Name,
 					Expression: exp,
+					Secret:     secret,
 				}
 
-				validator, err := withCache(cacheKey, func() (validator Validator, err error) {
-					factory, found := validatorFactoryMap[validatorFactoryMapKey{validatorName, field.Type.Kind()}]
+				validator, err := s.withCache(cacheKey, func() (validator Validator, err error) {
+					factory, found := s.validatorFactory(validatorName, validationType.Kind())
 					if !found {
 						return nil, fmt.Errorf(
 							"unrecognized validation exp: '%s' on struct field: '%s'",
@@ -52,24 +161,54 @@ func parseFromMap(tagName string, structPtr any, sourceMap map[string]LazyDecode
 						)
 					}
 
-					return factory(field.Name, rule)
+					validator, err = factory(field.Name, rule)
+					if err != nil || !secret {
+						return validator, err
+					}
+
+					return redactValidator(field.Name, exp, validator), nil
 				})
 				if err != nil {
 					return err
 				}
 
 				validations = append(validations, validator)
+				validationExps = append(validationExps, exp)
 			}
 		}
 
-		if sourceMap[key] == nil {
-			defaultYAML := field.Tags["default"]
+		value, err := s.lookupField(path, field, key, sourceMap, usedKeys)
+		if err != nil {
+			return err
+		}
+
+		var origin Origin
+		if value != nil && s.origins != nil {
+			origin = originOf(s.options.sourceName, value)
+		}
+
+		if value == nil && field.Tags["secretFile"] != "" {
+			content, err := readSecretFile(field.Tags["secretFile"])
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("error reading secret file of field '%s': %w", key, err)
+			}
+
+			// If the file doesn't exist we just fallback to the default value:
+			if err == nil {
+				value = scalarDecoder(content)
+				origin = Origin{Source: field.Tags["secretFile"]}
+			}
+		}
+
+		if value == nil {
+			defaultYAML := field.Tags[s.defaultTag]
 			if defaultYAML != "" {
 				err := yaml.Unmarshal([]byte(defaultYAML), field.Value)
 				if err != nil {
 					return fmt.Errorf(`error parsing "default" value as YAML: %s`, err)
 				}
 
+				s.recordOrigin(fieldPath, Origin{Default: true, Secret: secret})
 				return nil
 			}
 
@@ -82,34 +221,38 @@ func parseFromMap(tagName string, structPtr any, sourceMap map[string]LazyDecode
 
 			// If it is a struct we keep parsing its fields
 			// just to set the default values if they exist:
-			if field.Kind == reflect.Struct {
-				return parseFromMap(tagName, field.Value, map[string]LazyDecoder{})
+			if nestedStruct {
+				return s.parseStruct(fieldPath, field.Value, map[string]LazyDecoder{})
 			}
 
 			// If it is not required we can safely ignore it:
 			return nil
 		}
 
-		if field.Kind == reflect.Struct {
+		// Types with decode hooks are always decoded as a single value:
+		hook, hooked := s.options.decodeHooks[field.Type]
+
+		if nestedStruct && !hooked {
 			var data map[string]LazyDecoder
-			err := sourceMap[key].Decode(&data)
+			err := value.Decode(&data)
 			if err != nil {
 				return fmt.Errorf(
 					"can't map %T into nested struct %s of type %v",
-					sourceMap[key], field.Name, field.Type,
+					value, field.Name, field.Type,
 				)
 			}
 
-			return parseFromMap(tagName, field.Value, data)
+			return s.parseStruct(fieldPath, field.Value, data)
 		}
 
-		if field.Kind == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
+		isStructSlice := field.Kind == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct
+		if isStructSlice && containsStructs(field.Type.Elem()) && !hooked {
 			var data []LazyDecoder
-			err := sourceMap[key].Decode(&data)
+			err := value.Decode(&data)
 			if err != nil {
 				return fmt.Errorf(
 					"can't map %T into nested slice %s of type %v",
-					sourceMap[key], field.Name, field.Type,
+					value, field.Name, field.Type,
 				)
 			}
 
@@ -130,7 +273,7 @@ func parseFromMap(tagName string, structPtr any, sourceMap map[string]LazyDecode
 				elemPtr := sliceValue.Index(i).Addr().Interface()
 
 				// Recursively parse the struct
-				err = parseFromMap(tagName, elemPtr, itemMap)
+				err = s.parseStruct(fmt.Sprintf("%s[%d]", fieldPath, i), elemPtr, itemMap)
 				if err != nil {
 					return fmt.Errorf(
 						"error parsing element %d of slice %s: %v",
@@ -143,22 +286,340 @@ func parseFromMap(tagName string, structPtr any, sourceMap map[string]LazyDecode
 			return field.Set(sliceValue.Interface())
 		}
 
-		err := sourceMap[key].Decode(field.Value)
+		// Maps of structs are parsed item by item like maps on the root,
+		// but the validations of the map itself still run below:
+		isStringMap := field.Kind == reflect.Map && field.Type.Key().Kind() == reflect.String
+		parsedItems := isStringMap && containsStructs(field.Type) && !hooked
+		if parsedItems {
+			err := s.parseValue(fieldPath, reflect.ValueOf(field.Value).Elem(), value)
+			if err != nil {
+				return err
+			}
+		}
+
+		if s.origins != nil {
+			origin.EnvVar = cmp.Or(envVarReference(value), origin.EnvVar)
+		}
+
+		// The secrets inside the items were already resolved by parseStruct:
+		resolved := false
+		if !parsedItems {
+			value, resolved, err = resolveSecrets(value, field.Type, secret)
+			if err != nil {
+				return fmt.Errorf("error resolving secret value of field '%s': %w", key, err)
+			}
+		}
+
+		// Values loaded from secret references or decrypted are always treated as secrets:
+		if resolved && !secret {
+			secret = true
+			for i, validator := range validations {
+				validations[i] = redactValidator(field.Name, validationExps[i], validator)
+			}
+		}
+
+		if hooked {
+			err = decodeWithHook(hook, value, field.Value)
+		} else if !parsedItems {
+			err = value.Decode(field.Value)
+		}
+		if err != nil && secret {
+			// The decoding errors might contain the secret value:
+			return fmt.Errorf("can't decode secret field '%s' into type %v", key, field.Type)
+		}
 		if err != nil {
 			return err
 		}
 
+		origin.Secret = secret
+		s.recordOrigin(fieldPath, origin)
+
+		validationTarget := field.Value
+		if secretType {
+			validationTarget = field.Value.(secretValue).secretValuePtr()
+		}
+
 		// Run the validations only after decoding the value:
 		for _, validation := range validations {
-			errs = errors.Join(errs, validation(field.Value))
+			err := validation(validationTarget)
+			if err != nil && s.validationPositions {
+				if origin := originOf(s.options.sourceName, value); origin.Line > 0 {
+					err = positionError(origin.Source, origin.Line, origin.Column, err)
+				}
+			}
+			errs = errors.Join(errs, err)
 		}
 
 		return nil
 	})
 
+	// The version key is read by the migrations even if the struct has no field for it:
+	if path == "" && s.options.migrations != nil {
+		usedKeys[s.options.versionKey] = true
+	}
+
+	// If the iteration stopped early we can't tell which keys were used:
+	if s.options.strict && err == nil {
+		errs = errors.Join(errs, unknownKeysError(path, sourceMap, usedKeys))
+	}
+
 	return errors.Join(err, errs)
 }
 
+// lookupField finds the value of the field on the source map, either by
+// its key or by one of its aliases, reporting the use of deprecated keys.
+func (s *parseState) lookupField(
+	path string,
+	field structi.Field,
+	key string,
+	sourceMap map[string]LazyDecoder,
+	usedKeys map[string]bool,
+) (LazyDecoder, error) {
+	value, sourceKey := s.lookupKey(sourceMap, key)
+	usedKeys[sourceKey] = true
+
+	fromAlias := false
+	if field.Tags["aliases"] != "" {
+		for _, alias := range strings.Split(field.Tags["aliases"], ",") {
+			aliasValue, aliasKey := s.lookupKey(sourceMap, strings.TrimSpace(alias))
+			if aliasValue == nil {
+				continue
+			}
+			usedKeys[aliasKey] = true
+
+			if value != nil {
+				return nil, fmt.Errorf(
+					"conflicting keys '%s' and '%s', use only '%s'",
+					joinKeyPath(path, sourceKey), joinKeyPath(path, aliasKey), joinKeyPath(path, key),
+				)
+			}
+
+			value, sourceKey, fromAlias = aliasValue, aliasKey, true
+		}
+	}
+
+	deprecation := field.Tags["deprecated"]
+	if fromAlias && deprecation == "" {
+		deprecation = fmt.Sprintf("use '%s' instead", key)
+	}
+
+	if value != nil && deprecation != "" {
+		s.warn(Warning{
+			Path:    joinKeyPath(path, sourceKey),
+			Message: "key is deprecated: " + deprecation,
+		})
+	}
+
+	return value, nil
+}
+
+// fieldKey returns the key of the field on the source
+// map, or an empty string if the field should be ignored.
+func (s *parseState) fieldKey(field structi.Field) string {
+	return s.tagKey(field.Name, field.Tags)
+}
+
+// tagKey returns the key of a field from its name and tags.
+func (s *parseState) tagKey(fieldName string, tags map[string]string) string {
+	key := keyFromTag(tags[s.tagName])
+	if key == "" && s.options.namingStrategy != nil && !isSkipTag(tags[s.tagName]) {
+		return s.options.namingStrategy(fieldName)
+	}
+
+	return key
+}
+
+// keyFromTag returns the key written on a struct tag, ignoring the options
+// as in `json:"foo,omitempty"`, or an empty string if the tag has no key or
+// the field is skipped with `yaml:"-"`.
+func keyFromTag(tag string) string {
+	if isSkipTag(tag) {
+		return ""
+	}
+
+	key, _, _ := strings.Cut(tag, ",")
+	return key
+}
+
+func isSkipTag(tag string) bool {
+	key, _, _ := strings.Cut(tag, ",")
+	return key == "-"
+}
+
+// lookupKey finds the value of a key on the source map,
+// also returning the key as written on the source.
+func (s *parseState) lookupKey(sourceMap map[string]LazyDecoder, key string) (LazyDecoder, string) {
+	value, found := sourceMap[key]
+	if found || (!s.options.caseInsensitive && !s.options.looseKeys) {
+		return value, key
+	}
+
+	// The keys are sorted so the match is deterministic:
+	sourceKeys := make([]string, 0, len(sourceMap))
+	for sourceKey := range sourceMap {
+		sourceKeys = append(sourceKeys, sourceKey)
+	}
+	sort.Strings(sourceKeys)
+
+	for _, sourceKey := range sourceKeys {
+		if s.keysMatch(sourceKey, key) {
+			return sourceMap[sourceKey], sourceKey
+		}
+	}
+
+	return nil, key
+}
+
+// keysMatch checks if a key of the source matches a key of the
+// struct tags, which depends on the WithCaseInsensitiveKeys
+// and WithLooseKeys options.
+func (s *parseState) keysMatch(sourceKey string, key string) bool {
+	switch {
+	case sourceKey == key:
+		return true
+	case s.options.looseKeys:
+		return normalizeKey(sourceKey) == normalizeKey(key)
+	case s.options.caseInsensitive:
+		return strings.EqualFold(sourceKey, key)
+	}
+
+	return false
+}
+
+func unknownKeysError(path string, sourceMap map[string]LazyDecoder, usedKeys map[string]bool) error {
+	var unknownKeys []string
+	for key := range sourceMap {
+		if !usedKeys[key] {
+			unknownKeys = append(unknownKeys, "'"+joinKeyPath(path, key)+"'")
+		}
+	}
+	if len(unknownKeys) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknownKeys)
+	return fmt.Errorf("unknown keys: %s", strings.Join(unknownKeys, ", "))
+}
+
+func decodeWithHook(hook func(LazyDecoder) (any, error), value LazyDecoder, target any) error {
+	result, err := hook(value)
+	if err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(target).Elem()
+	if result == nil {
+		// Hooks for interface types might return nil
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	v.Set(reflect.ValueOf(result))
+	return nil
+}
+
+// parseValue decodes the value into target, which must be addressable,
+// running the defaults and validations of all structs found inside it.
+func (s *parseState) parseValue(path string, target reflect.Value, value LazyDecoder) error {
+	if !containsStructs(target.Type()) {
+		return value.Decode(target.Addr().Interface())
+	}
+
+	switch target.Kind() {
+	case reflect.Struct:
+		var data map[string]LazyDecoder
+		err := value.Decode(&data)
+		if err != nil {
+			return err
+		}
+
+		return s.parseStruct(path, target.Addr().Interface(), data)
+
+	case reflect.Slice, reflect.Array:
+		var items []LazyDecoder
+		err := value.Decode(&items)
+		if err != nil {
+			return err
+		}
+
+		if target.Kind() == reflect.Array && len(items) != target.Len() {
+			return fmt.Errorf("expected %d elements but got %d", target.Len(), len(items))
+		}
+		if target.Kind() == reflect.Slice {
+			target.Set(reflect.MakeSlice(target.Type(), len(items), len(items)))
+		}
+
+		var errs error
+		for i, item := range items {
+			err := s.parseValue(fmt.Sprintf("%s[%d]", path, i), target.Index(i), item)
+			if err != nil {
+				errs = errors.Join(errs, fmt.Errorf("error parsing element %d: %w", i, err))
+			}
+		}
+		return errs
+
+	case reflect.Map:
+		if target.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map key type %v, only string keys are supported", target.Type().Key())
+		}
+
+		var items map[string]LazyDecoder
+		err := value.Decode(&items)
+		if err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(items))
+		for key := range items {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		target.Set(reflect.MakeMapWithSize(target.Type(), len(items)))
+
+		var errs error
+		for _, key := range keys {
+			elem := reflect.New(target.Type().Elem()).Elem()
+			err := s.parseValue(joinKeyPath(path, key), elem, items[key])
+			if err != nil {
+				errs = errors.Join(errs, fmt.Errorf("error parsing key '%s': %w", key, err))
+				continue
+			}
+
+			target.SetMapIndex(reflect.ValueOf(key).Convert(target.Type().Key()), elem)
+		}
+		return errs
+	}
+
+	return value.Decode(target.Addr().Interface())
+}
+
+// containsStructs reports if the type is a struct or a collection of structs,
+// which need to be parsed field by field for applying defaults and validations.
+func containsStructs(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		return !isSecretType(t) && !hasUnmarshaler(t)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return containsStructs(t.Elem())
+	}
+	return false
+}
+
+// hasUnmarshaler reports if t decodes itself, e.g. time.Time,
+// in which case it is decoded as a single value.
+func hasUnmarshaler(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return pt.Implements(yamlUnmarshalerType) || pt.Implements(textUnmarshalerType)
+}
+
+func joinKeyPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
 func extractValidatorNameAndRule(exp string) (validatorName string, rule string) {
 	if exp == "" {
 		return "", ""
@@ -189,12 +650,13 @@ type cacheKey struct {
 
 	// If the expression is the same even on different structs we can reuse the same key
 	Expression string
-}
 
-var validatorCache sync.Map
+	// Validators of secret fields must not include the value on the error messages
+	Secret bool
+}
 
-func withCache(cacheKey cacheKey, fn func() (Validator, error)) (Validator, error) {
-	if v, _ := validatorCache.Load(cacheKey); v != nil {
+func (s *parseState) withCache(cacheKey cacheKey, fn func() (Validator, error)) (Validator, error) {
+	if v, _ := s.cache.Load(cacheKey); v != nil {
 		return v.(Validator), nil
 	}
 
@@ -203,7 +665,7 @@ func withCache(cacheKey cacheKey, fn func() (Validator, error)) (Validator, erro
 		return nil, err
 	}
 
-	validatorCache.Store(cacheKey, validator)
+	s.cache.Store(cacheKey, validator)
 
 	return validator, nil
 }
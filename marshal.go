@@ -0,0 +1,134 @@
+package kparse
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/vingarcia/structi"
+	"gopkg.in/yaml.v3"
+)
+
+// MarshalOptions customizes the output of Marshal.
+type MarshalOptions struct {
+	// OmitDefaults omits the fields whose values are
+	// equal to the values on their `default` tags.
+	OmitDefaults bool
+}
+
+// Marshal writes the struct pointed by structPtr as a config file in the
+// given format, which can be either "yaml" or "json", using the tag with
+// the same name as the format for the keys.
+//
+// The output is parsed back before being returned, so Marshal fails
+// with the same errors Parse would if the config is not valid, e.g.
+// when a required field is missing or a validation fails.
+//
+// Unlike Dump, secret values are written as they are, so the
+// output should be handled with the same care as the secrets.
+func Marshal(structPtr any, format string, opts MarshalOptions) ([]byte, error) {
+	if format != "yaml" && format != "json" {
+		return nil, fmt.Errorf("unsupported format: '%s', expected one of: yaml or json", format)
+	}
+
+	v := reflect.ValueOf(structPtr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected non-nil struct pointer but got: %T", structPtr)
+	}
+
+	node, err := valueNode(format, v.Elem(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.OmitDefaults {
+		err = omitDefaultValues(format, v.Elem(), node)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var output []byte
+	if format == "json" {
+		output, err = encodeNodeAsJSON(node)
+	} else {
+		output, err = encodeNodeAsYAML(node)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Parsing the output into a new struct runs all the validations
+	// and guarantees we never write a config that can't be parsed:
+	parsed := reflect.New(v.Elem().Type()).Interface()
+	if format == "json" {
+		err = ParseJSON(output, parsed)
+	} else {
+		err = ParseYAML(output, parsed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("refusing to write invalid config: %w", err)
+	}
+
+	return output, nil
+}
+
+// omitDefaultValues removes from the mapping node built by valueNode
+// all the keys whose values are equal to their default values.
+func omitDefaultValues(tagName string, v reflect.Value, node *yaml.Node) error {
+	info, err := structi.GetStructInfo(v.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, field := range info.Fields {
+		key := keyFromTag(field.Tags[tagName])
+		if key == "" {
+			continue
+		}
+
+		i := mappingKeyIndex(node, key)
+		if i < 0 {
+			continue
+		}
+		fieldValue := v.FieldByName(field.Name)
+
+		if field.Tags["default"] != "" {
+			defaultValue := reflect.New(field.Type)
+			err := yaml.Unmarshal([]byte(field.Tags["default"]), defaultValue.Interface())
+			if err != nil {
+				return fmt.Errorf(`error parsing "default" value of field '%s' as YAML: %s`, field.Name, err)
+			}
+
+			if reflect.DeepEqual(fieldValue.Interface(), defaultValue.Elem().Interface()) {
+				node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			}
+			continue
+		}
+
+		valueNode := node.Content[i+1]
+		switch {
+		case field.Kind == reflect.Struct && !isSecretType(field.Type):
+			err = omitDefaultValues(tagName, fieldValue, valueNode)
+		case field.Kind == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			for j := 0; j < fieldValue.Len() && err == nil; j++ {
+				err = omitDefaultValues(tagName, fieldValue.Index(j), valueNode.Content[j])
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mappingKeyIndex returns the index of the key on the
+// contents of a mapping node or -1 if it is not found.
+func mappingKeyIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
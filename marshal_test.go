@@ -0,0 +1,165 @@
+package kparse
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestMarshal(t *testing.T) {
+	type Config struct {
+		Host       string         `yaml:"host" json:"host" validate:"required"`
+		MaxRetries int            `yaml:"maxRetries" json:"maxRetries" default:"3" validate:"<=10"`
+		Password   Secret[string] `yaml:"password" json:"password"`
+		Database   struct {
+			Port int    `yaml:"port" json:"port" default:"5432"`
+			Name string `yaml:"name" json:"name"`
+		} `yaml:"database" json:"database"`
+		Users []struct {
+			Name string `yaml:"name" json:"name"`
+			Role string `yaml:"role" json:"role" default:"viewer"`
+		} `yaml:"users" json:"users"`
+		Ignored string
+		Cache   int    `yaml:"-" json:"-"`
+		Session string `yaml:"-,omitempty" json:"-"`
+	}
+
+	newConfig := func() Config {
+		var config Config
+		err := ParseYAML([]byte(`host: fakeHost
+password: fakePassword
+database:
+  name: fakeName
+users:
+  - name: fakeUser1
+  - name: fakeUser2
+    role: admin
+`), &config)
+		tt.AssertNoErr(t, err)
+
+		// Fields skipped with `-` are never written:
+		config.Cache = 42
+		config.Session = "fakeSession"
+		return config
+	}
+
+	tests := []struct {
+		desc               string
+		edit               func(config *Config)
+		format             string
+		opts               MarshalOptions
+		expectedOutput     string
+		expectErrToContain []string
+	}{
+		{
+			desc:   "should write yaml using the yaml tags",
+			format: "yaml",
+			expectedOutput: `host: fakeHost
+maxRetries: 3
+password: fakePassword
+database:
+  port: 5432
+  name: fakeName
+users:
+  - name: fakeUser1
+    role: viewer
+  - name: fakeUser2
+    role: admin
+`,
+		},
+		{
+			desc:   "should omit values equal to their defaults",
+			format: "yaml",
+			edit: func(config *Config) {
+				config.Database.Port = 5433
+			},
+			opts: MarshalOptions{OmitDefaults: true},
+			expectedOutput: `host: fakeHost
+password: fakePassword
+database:
+  port: 5433
+  name: fakeName
+users:
+  - name: fakeUser1
+  - name: fakeUser2
+    role: admin
+`,
+		},
+		{
+			desc:   "should write json using the json tags",
+			format: "json",
+			opts:   MarshalOptions{OmitDefaults: true},
+			edit: func(config *Config) {
+				config.Users = nil
+			},
+			expectedOutput: `{
+  "host": "fakeHost",
+  "password": "fakePassword",
+  "database": {
+    "name": "fakeName"
+  },
+  "users": null
+}
+`,
+		},
+		{
+			desc:   "should refuse to write configs that fail the validations",
+			format: "yaml",
+			edit: func(config *Config) {
+				config.MaxRetries = 11
+			},
+			expectErrToContain: []string{"invalid config", "MaxRetries", "<= 10"},
+		},
+		{
+			desc:               "should report unsupported formats",
+			format:             "toml",
+			expectErrToContain: []string{"unsupported format", "toml"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			config := newConfig()
+			if test.edit != nil {
+				test.edit(&config)
+			}
+
+			output, err := Marshal(&config, test.format, test.opts)
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, string(output), test.expectedOutput)
+		})
+	}
+
+	t.Run("should write types with their own marshalers as scalars", func(t *testing.T) {
+		type Config struct {
+			StartedAt time.Time `yaml:"startedAt" json:"startedAt"`
+			Address   net.IP    `yaml:"address" json:"address"`
+		}
+
+		for _, format := range []string{"yaml", "json"} {
+			config := Config{
+				StartedAt: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+				Address:   net.ParseIP("10.0.0.1"),
+			}
+
+			output, err := Marshal(&config, format, MarshalOptions{})
+			tt.AssertNoErr(t, err)
+
+			var parsed Config
+			if format == "json" {
+				err = ParseJSON(output, &parsed)
+			} else {
+				err = ParseYAML(output, &parsed)
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, parsed.StartedAt, config.StartedAt)
+			tt.AssertEqual(t, parsed.Address.String(), "10.0.0.1")
+		}
+	})
+}
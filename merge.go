@@ -0,0 +1,341 @@
+package kparse
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/vingarcia/structi"
+)
+
+// ListMergeStrategy sets how lists are merged when the
+// same key is set to a list on more than one source.
+type ListMergeStrategy struct {
+	appendItems bool
+	key         string
+}
+
+var (
+	// ReplaceLists uses the list of the last source, which is the default.
+	ReplaceLists = ListMergeStrategy{}
+
+	// AppendLists concatenates the lists of all sources.
+	AppendLists = ListMergeStrategy{appendItems: true}
+)
+
+// MergeListsByKey merges the items of lists of maps that have the same
+// value on the given key, and appends the items with new values, e.g.
+// MergeListsByKey("name") merges the routes with the same name. Lists
+// of other values are appended skipping the values already present.
+func MergeListsByKey(key string) ListMergeStrategy {
+	return ListMergeStrategy{key: key}
+}
+
+// WithListMergeStrategy sets how Parser.Load, ParseDir and ParseWithProfile
+// merge lists, by default the lists of the last source replace the previous ones.
+//
+// Fields can also choose their own strategy with the `merge` tag:
+//
+//	Domains []string          `yaml:"domains" merge:"append"`
+//	Routes  []Route           `yaml:"routes" merge:"by=name"`
+//	Labels  map[string]string `yaml:"labels" merge:"replace"`
+//
+// Where "replace" also makes maps be replaced instead of merged.
+func WithListMergeStrategy(strategy ListMergeStrategy) Option {
+	return func(opts *parseOptions) {
+		opts.listMergeStrategy = strategy
+	}
+}
+
+// mergeRule is how a value is merged, set by the `merge` tag of its field.
+type mergeRule struct {
+	lists ListMergeStrategy
+
+	// replace makes maps be replaced instead of merged
+	replace bool
+}
+
+func parseMergeTag(tag string) (mergeRule, error) {
+	switch {
+	case tag == "replace":
+		return mergeRule{replace: true}, nil
+	case tag == "append":
+		return mergeRule{lists: AppendLists}, nil
+	case strings.HasPrefix(tag, "by=") && len(tag) > len("by="):
+		return mergeRule{lists: MergeListsByKey(strings.TrimPrefix(tag, "by="))}, nil
+	}
+
+	return mergeRule{}, fmt.Errorf("invalid merge tag '%s', expected 'append', 'replace' or 'by=<key>'", tag)
+}
+
+// layerMerger merges the values of multiple sources keeping track
+// of which source set each value, so conflicts can name both sources.
+type layerMerger struct {
+	state       *parseState
+	rootType    reflect.Type
+	defaultRule mergeRule
+
+	// reportConflicts makes values changing from a map, list or single value
+	// into another an error, and produces warnings for overridden values
+	reportConflicts bool
+
+	// files maps the key paths to the sources that set them, values
+	// not found here are described by their origins instead
+	files map[string]string
+}
+
+// newLayerMerger creates a layerMerger for parsing into target, whose
+// type is used for finding the `merge` tags of the fields.
+func newLayerMerger(state *parseState, target any) *layerMerger {
+	return &layerMerger{
+		state:       state,
+		rootType:    reflect.TypeOf(target),
+		defaultRule: mergeRule{lists: state.options.listMergeStrategy},
+		files:       map[string]string{},
+	}
+}
+
+// mergeLayer merges the values of the named source over the base values.
+func (m *layerMerger) mergeLayer(base LazyDecoder, override LazyDecoder, source string) (LazyDecoder, error) {
+	// Empty sources have nothing to merge:
+	if override == nil {
+		return base, nil
+	}
+
+	return m.merge("", m.rootType, m.defaultRule, base, override, source)
+}
+
+func (m *layerMerger) fileOf(path string, value LazyDecoder) string {
+	return cmp.Or(m.files[path], originOf("", value).Source)
+}
+
+// merge merges the override value set by file over the base value, typ
+// is the type the value will be parsed into, or nil if it is unknown.
+func (m *layerMerger) merge(
+	path string,
+	typ reflect.Type,
+	rule mergeRule,
+	base LazyDecoder,
+	override LazyDecoder,
+	file string,
+) (LazyDecoder, error) {
+	if base == nil || override == nil {
+		m.files[path] = file
+		return override, nil
+	}
+
+	baseFile := m.fileOf(path, base)
+
+	var baseMap, overrideMap map[string]LazyDecoder
+	var baseList, overrideList []LazyDecoder
+	baseKind := decoderKind(base, &baseMap, &baseList)
+	overrideKind := decoderKind(override, &overrideMap, &overrideList)
+	if baseKind != overrideKind && m.reportConflicts {
+		return nil, fmt.Errorf(
+			"conflicting values for key '%s': %s on '%s' and %s on '%s'",
+			cmp.Or(path, "(root)"), baseKind, baseFile, overrideKind, file,
+		)
+	}
+
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch {
+	case baseKind != overrideKind || rule.replace:
+		// Replaced below
+
+	case baseMap != nil:
+		merged := maps.Clone(baseMap)
+		for _, key := range slices.Sorted(maps.Keys(overrideMap)) {
+			keyPath := joinKeyPath(path, key)
+
+			current, found := merged[key]
+			if !found {
+				m.files[keyPath] = file
+				merged[key] = overrideMap[key]
+				continue
+			}
+
+			keyType, keyRule, err := m.keyRule(typ, rule, key)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := m.merge(keyPath, keyType, keyRule, current, overrideMap[key], file)
+			if err != nil {
+				return nil, err
+			}
+			merged[key] = value
+		}
+
+		return mapDecoder(merged), nil
+
+	case baseList != nil && rule.lists.appendItems:
+		for i := range overrideList {
+			m.files[fmt.Sprintf("%s[%d]", path, len(baseList)+i)] = file
+		}
+
+		return sliceDecoder(append(slices.Clip(baseList), overrideList...)), nil
+
+	case baseList != nil && rule.lists.key != "":
+		var itemType reflect.Type
+		if typ != nil && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) {
+			itemType = typ.Elem()
+		}
+
+		return m.mergeByKey(path, itemType, rule.lists.key, baseList, overrideList, file)
+	}
+
+	if m.reportConflicts && !decodersEqual(base, override) {
+		m.state.warn(Warning{
+			Path:    path,
+			Message: fmt.Sprintf("value from '%s' is overridden by '%s'", baseFile, file),
+		})
+	}
+
+	for key := range m.files {
+		if strings.HasPrefix(key, path+"[") || strings.HasPrefix(key, path+".") {
+			delete(m.files, key)
+		}
+	}
+	m.files[path] = file
+
+	return override, nil
+}
+
+// keyRule returns the type and merge rule of a key of a map of type typ,
+// the values of map types use the same rule of the map itself.
+func (m *layerMerger) keyRule(typ reflect.Type, rule mergeRule, key string) (reflect.Type, mergeRule, error) {
+	if typ == nil {
+		return nil, m.defaultRule, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Map:
+		return typ.Elem(), rule, nil
+
+	case reflect.Struct:
+		info, err := structi.GetStructInfo(typ)
+		if err != nil {
+			return nil, m.defaultRule, nil
+		}
+
+		for _, field := range info.Fields {
+			fieldKeys := []string{m.state.tagKey(field.Name, field.Tags)}
+			if field.Tags["aliases"] != "" {
+				fieldKeys = append(fieldKeys, strings.Split(field.Tags["aliases"], ",")...)
+			}
+
+			matches := slices.ContainsFunc(fieldKeys, func(fieldKey string) bool {
+				fieldKey = strings.TrimSpace(fieldKey)
+				return fieldKey != "" && m.state.keysMatch(key, fieldKey)
+			})
+			if !matches {
+				continue
+			}
+
+			if field.Tags["merge"] == "" {
+				return field.Type, m.defaultRule, nil
+			}
+
+			fieldRule, err := parseMergeTag(field.Tags["merge"])
+			if err != nil {
+				return nil, mergeRule{}, fmt.Errorf("error on field '%s': %w", field.Name, err)
+			}
+			return field.Type, fieldRule, nil
+		}
+	}
+
+	return nil, m.defaultRule, nil
+}
+
+func (m *layerMerger) mergeByKey(
+	path string,
+	itemType reflect.Type,
+	key string,
+	baseList []LazyDecoder,
+	overrideList []LazyDecoder,
+	file string,
+) (LazyDecoder, error) {
+	merged := slices.Clone(baseList)
+	for i, item := range overrideList {
+		itemKey, err := listItemKey(item, key)
+		if err != nil {
+			return nil, fmt.Errorf("error merging item %d of '%s' on '%s': %w", i, path, file, err)
+		}
+
+		index := slices.IndexFunc(merged, func(current LazyDecoder) bool {
+			currentKey, err := listItemKey(current, key)
+			return err == nil && currentKey == itemKey
+		})
+
+		if index == -1 {
+			m.files[fmt.Sprintf("%s[%d]", path, len(merged))] = file
+			merged = append(merged, item)
+			continue
+		}
+
+		value, err := m.merge(fmt.Sprintf("%s[%d]", path, index), itemType, m.defaultRule, merged[index], item, file)
+		if err != nil {
+			return nil, err
+		}
+		merged[index] = value
+	}
+
+	return sliceDecoder(merged), nil
+}
+
+// decoderKind decodes the value into m or list and describes what it is.
+func decoderKind(value LazyDecoder, m *map[string]LazyDecoder, list *[]LazyDecoder) string {
+	if value.Decode(m) == nil && *m != nil {
+		return "a map"
+	}
+	*m = nil
+
+	if value.Decode(list) == nil && *list != nil {
+		return "a list"
+	}
+	*list = nil
+
+	return "a single value"
+}
+
+// listItemKey returns the value of the key of maps, other
+// values are their own keys, so lists of strings have no duplicates.
+func listItemKey(item LazyDecoder, key string) (itemKey listKey, _ error) {
+	if item == nil {
+		return listKey{}, nil
+	}
+
+	var m map[string]LazyDecoder
+	if item.Decode(&m) == nil && m != nil {
+		if m[key] == nil {
+			return listKey{}, fmt.Errorf("expected a map with the '%s' key", key)
+		}
+		item = m[key]
+		itemKey.isMap = true
+	}
+
+	var value any
+	err := item.Decode(&value)
+	itemKey.value = fmt.Sprint(value)
+	return itemKey, err
+}
+
+type listKey struct {
+	isMap bool
+	value string
+}
+
+func decodersEqual(a LazyDecoder, b LazyDecoder) bool {
+	var aValue, bValue any
+	if a.Decode(&aValue) != nil || b.Decode(&bValue) != nil {
+		return false
+	}
+
+	return fmt.Sprint(aValue) == fmt.Sprint(bValue)
+}
@@ -0,0 +1,151 @@
+package kparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestMergeTags(t *testing.T) {
+	type Route struct {
+		Name    string   `yaml:"name" validate:"required"`
+		Path    string   `yaml:"path"`
+		Methods []string `yaml:"methods" merge:"append"`
+		Timeout int      `yaml:"timeout" default:"30"`
+	}
+
+	type Config struct {
+		Domains  []string            `yaml:"domains" merge:"append"`
+		Aliases  []string            `yaml:"aliases"`
+		Routes   []Route             `yaml:"routes" merge:"by=name"`
+		Labels   map[string]string   `yaml:"labels" merge:"replace"`
+		Settings map[string]string   `yaml:"settings"`
+		Groups   map[string][]string `yaml:"groups" merge:"append"`
+	}
+
+	base := YAMLSource("base.yaml", []byte(`
+domains: [a.com]
+aliases: [x.com]
+routes:
+  - name: users
+    path: /users
+    methods: [GET]
+  - name: orders
+    path: /orders
+labels:
+  team: core
+settings:
+  theme: dark
+groups:
+  admins: [alice]
+`))
+
+	override := JSONSource("override.json", []byte(`{
+	"domains": ["b.com"],
+	"aliases": ["y.com"],
+	"routes": [{"name": "users", "methods": ["POST"], "timeout": 60}, {"name": "health", "path": "/health"}],
+	"labels": {"env": "prod"},
+	"settings": {"lang": "en"},
+	"groups": {"admins": ["bob"], "users": ["carol"]}
+}`))
+
+	tests := []struct {
+		desc               string
+		opts               []Option
+		expectedConfig     Config
+		expectErrToContain []string
+	}{
+		{
+			desc: "should merge each field according to its merge tag",
+			opts: []Option{WithSources(base, override)},
+			expectedConfig: Config{
+				Domains: []string{"a.com", "b.com"},
+				Aliases: []string{"y.com"},
+				Routes: []Route{
+					{Name: "users", Path: "/users", Methods: []string{"GET", "POST"}, Timeout: 60},
+					{Name: "orders", Path: "/orders", Timeout: 30},
+					{Name: "health", Path: "/health", Timeout: 30},
+				},
+				Labels:   map[string]string{"env": "prod"},
+				Settings: map[string]string{"theme": "dark", "lang": "en"},
+				Groups:   map[string][]string{"admins": {"alice", "bob"}, "users": {"carol"}},
+			},
+		},
+		{
+			desc: "should use the list merge strategy for fields without merge tags",
+			opts: []Option{WithSources(base, override), WithListMergeStrategy(AppendLists)},
+			expectedConfig: Config{
+				Domains: []string{"a.com", "b.com"},
+				Aliases: []string{"x.com", "y.com"},
+				Routes: []Route{
+					{Name: "users", Path: "/users", Methods: []string{"GET", "POST"}, Timeout: 60},
+					{Name: "orders", Path: "/orders", Timeout: 30},
+					{Name: "health", Path: "/health", Timeout: 30},
+				},
+				Labels:   map[string]string{"env": "prod"},
+				Settings: map[string]string{"theme": "dark", "lang": "en"},
+				Groups:   map[string][]string{"admins": {"alice", "bob"}, "users": {"carol"}},
+			},
+		},
+		{
+			desc: "should report items missing the merge key",
+			opts: []Option{WithSources(base, YAMLSource("broken.yaml", []byte("routes: [{path: /users}]\n")))},
+			expectErrToContain: []string{
+				"error loading source 'broken.yaml'",
+				"error merging item 0 of 'routes' on 'broken.yaml'",
+				"expected a map with the 'name' key",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var config Config
+			err := NewParser().Load(&config, test.opts...)
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, config, test.expectedConfig)
+		})
+	}
+
+	t.Run("should report invalid merge tags", func(t *testing.T) {
+		var config struct {
+			Domains []string `yaml:"domains" merge:"prepend"`
+		}
+		err := NewParser().Load(&config, WithSources(
+			YAMLSource("a.yaml", []byte("domains: [a.com]\n")),
+			YAMLSource("b.yaml", []byte("domains: [b.com]\n")),
+		))
+		tt.AssertErrContains(t, err, "error on field 'Domains'", "invalid merge tag 'prepend'")
+	})
+
+	t.Run("should respect the merge tags on ParseDir and ParseWithProfile", func(t *testing.T) {
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "10-base.yaml"), []byte("domains: [a.com]\nprofiles:\n  prod:\n    domains: [c.com]\n"), 0o644)
+		tt.AssertNoErr(t, err)
+		err = os.WriteFile(filepath.Join(dir, "20-override.yaml"), []byte("domains: [b.com]\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config struct {
+			Domains []string `yaml:"domains" merge:"append"`
+		}
+		err = ParseDir(dir, &config, WithDirPattern("*-override.yaml"))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.Domains, []string{"b.com"})
+
+		config.Domains = nil
+		err = ParseDir(dir, &config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.Domains, []string{"a.com", "b.com"})
+
+		config.Domains = nil
+		err = ParseWithProfile(filepath.Join(dir, "10-base.yaml"), "prod", &config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.Domains, []string{"a.com", "c.com"})
+	})
+}
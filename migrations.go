@@ -0,0 +1,141 @@
+package kparse
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Migration converts the raw values of a config from
+// one version to the next by changing the map in place.
+type Migration func(raw map[string]any) error
+
+// WithMigrations upgrades old config files before parsing them, the
+// version is read from the versionKey and migrations[v] converts a
+// config of version v into version v+1, e.g.:
+//
+//	kparse.WithMigrations("version", map[int]kparse.Migration{
+//		1: func(raw map[string]any) error {
+//			raw["baseURL"] = raw["baseUrl"]
+//			delete(raw, "baseUrl")
+//			return nil
+//		},
+//	})
+//
+// The current version is the last migrated version plus one, and configs
+// without the versionKey are considered to be of the first version.
+//
+// Migrated values have no line numbers on the origins recorded by WithOrigins.
+func WithMigrations(versionKey string, migrations map[int]Migration) Option {
+	return func(opts *parseOptions) {
+		opts.versionKey = versionKey
+		opts.migrations = migrations
+	}
+}
+
+// WithMigratedFile writes the config to the given path after migrating it,
+// so the old file can be replaced. Nothing is written if no migrations ran.
+//
+// The file uses the format being parsed, e.g. YAML for ParseYAMLFile, but
+// since migrations work on maps the keys are sorted and comments are lost.
+func WithMigratedFile(path string) Option {
+	return func(opts *parseOptions) {
+		opts.migratedFilePath = path
+	}
+}
+
+// migrate runs the migrations needed for upgrading the value to the current
+// version, returning the migrated node or nil if no migrations were needed.
+func (s *parseState) migrate(value LazyDecoder) (*yaml.Node, error) {
+	var raw map[string]any
+	err := value.Decode(&raw)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the config version: %w", err)
+	}
+	if raw == nil {
+		raw = map[string]any{}
+	}
+
+	versions := slices.Sorted(maps.Keys(s.options.migrations))
+	if len(versions) == 0 {
+		return nil, nil
+	}
+	firstVersion, currentVersion := versions[0], versions[len(versions)-1]+1
+
+	version := firstVersion
+	if rawVersion, found := raw[s.options.versionKey]; found {
+		version, err = decodeVersion(rawVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config version on key '%s': %w", s.options.versionKey, err)
+		}
+	}
+
+	if version == currentVersion {
+		return nil, nil
+	}
+	if version > currentVersion || version < firstVersion {
+		return nil, fmt.Errorf(
+			"unsupported config version %d, expected a version from %d to %d",
+			version, firstVersion, currentVersion,
+		)
+	}
+
+	for ; version < currentVersion; version++ {
+		migration, found := s.options.migrations[version]
+		if !found {
+			return nil, fmt.Errorf("no migration registered from version %d to %d", version, version+1)
+		}
+
+		err := migration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("error migrating config from version %d to %d: %w", version, version+1, err)
+		}
+	}
+	raw[s.options.versionKey] = currentVersion
+
+	var node yaml.Node
+	err = node.Encode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding migrated config: %w", err)
+	}
+
+	return &node, nil
+}
+
+func (s *parseState) writeMigratedFile(node *yaml.Node) error {
+	var file []byte
+	var err error
+	switch s.format {
+	case "yaml":
+		file, err = encodeNodeAsYAML(node)
+	case "json":
+		file, err = encodeNodeAsJSON(node)
+	default:
+		return fmt.Errorf("can't write migrated files in the '%s' format", s.format)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(s.options.migratedFilePath, file, 0o644)
+	if err != nil {
+		return fmt.Errorf("error writing migrated file: %w", err)
+	}
+
+	return nil
+}
+
+func decodeVersion(rawVersion any) (int, error) {
+	var node yaml.Node
+	err := node.Encode(rawVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	err = node.Decode(&version)
+	return version, err
+}
@@ -0,0 +1,209 @@
+package kparse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestMigrations(t *testing.T) {
+	type Config struct {
+		Version  int    `yaml:"version" json:"version"`
+		BaseURL  string `yaml:"baseURL" json:"baseURL" validate:"required"`
+		Database struct {
+			Host string `yaml:"host" json:"host"`
+			Port int    `yaml:"port" json:"port" default:"5432"`
+		} `yaml:"database" json:"database"`
+	}
+
+	migrations := map[int]Migration{
+		// v1 -> v2: renamed baseUrl to baseURL
+		1: func(raw map[string]any) error {
+			if baseURL, found := raw["baseUrl"]; found {
+				raw["baseURL"] = baseURL
+				delete(raw, "baseUrl")
+			}
+			return nil
+		},
+		// v2 -> v3: moved dbHost into database.host
+		2: func(raw map[string]any) error {
+			dbHost, found := raw["dbHost"]
+			if !found {
+				return errors.New("missing dbHost")
+			}
+			raw["database"] = map[string]any{"host": dbHost}
+			delete(raw, "dbHost")
+			return nil
+		},
+	}
+
+	tests := []struct {
+		desc               string
+		format             string
+		input              string
+		expectedConfig     Config
+		expectErrToContain []string
+	}{
+		{
+			desc:   "should migrate files from the first version",
+			format: "yaml",
+			input: `version: 1
+baseUrl: https://example.com
+dbHost: fakeHost
+`,
+			expectedConfig: Config{
+				Version: 3,
+				BaseURL: "https://example.com",
+				Database: struct {
+					Host string `yaml:"host" json:"host"`
+					Port int    `yaml:"port" json:"port" default:"5432"`
+				}{Host: "fakeHost", Port: 5432},
+			},
+		},
+		{
+			desc:   "should treat files without versions as the first version",
+			format: "json",
+			input:  `{"baseUrl": "https://example.com", "dbHost": "fakeHost"}`,
+			expectedConfig: Config{
+				Version: 3,
+				BaseURL: "https://example.com",
+				Database: struct {
+					Host string `yaml:"host" json:"host"`
+					Port int    `yaml:"port" json:"port" default:"5432"`
+				}{Host: "fakeHost", Port: 5432},
+			},
+		},
+		{
+			desc:   "should only run the needed migrations",
+			format: "json",
+			input:  `{"version": 2, "baseURL": "https://example.com", "dbHost": "fakeHost"}`,
+			expectedConfig: Config{
+				Version: 3,
+				BaseURL: "https://example.com",
+				Database: struct {
+					Host string `yaml:"host" json:"host"`
+					Port int    `yaml:"port" json:"port" default:"5432"`
+				}{Host: "fakeHost", Port: 5432},
+			},
+		},
+		{
+			desc:   "should not change files on the current version",
+			format: "yaml",
+			input: `version: 3
+baseURL: https://example.com
+`,
+			expectedConfig: Config{
+				Version: 3,
+				BaseURL: "https://example.com",
+				Database: struct {
+					Host string `yaml:"host" json:"host"`
+					Port int    `yaml:"port" json:"port" default:"5432"`
+				}{Port: 5432},
+			},
+		},
+		{
+			desc:               "should report errors from the migrations",
+			format:             "yaml",
+			input:              `{version: 2, baseURL: https://example.com}`,
+			expectErrToContain: []string{"error migrating config from version 2 to 3", "missing dbHost"},
+		},
+		{
+			desc:               "should report unsupported versions",
+			format:             "yaml",
+			input:              `{version: 4, baseURL: https://example.com}`,
+			expectErrToContain: []string{"unsupported config version 4", "from 1 to 3"},
+		},
+		{
+			desc:               "should report invalid versions",
+			format:             "yaml",
+			input:              `{version: latest, baseURL: https://example.com}`,
+			expectErrToContain: []string{"invalid config version on key 'version'"},
+		},
+		{
+			desc:               "should validate the migrated values",
+			format:             "yaml",
+			input:              `{version: 2, dbHost: fakeHost}`,
+			expectErrToContain: []string{"missing required field 'baseURL'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var config Config
+			var err error
+			if test.format == "json" {
+				err = ParseJSON([]byte(test.input), &config, WithMigrations("version", migrations))
+			} else {
+				err = ParseYAML([]byte(test.input), &config, WithMigrations("version", migrations))
+			}
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, config, test.expectedConfig)
+		})
+	}
+
+	t.Run("should accept the version key in strict mode", func(t *testing.T) {
+		var config struct {
+			BaseURL string `yaml:"baseURL"`
+		}
+		strictMigrations := WithMigrations("version", map[int]Migration{
+			1: func(raw map[string]any) error {
+				raw["baseURL"] = raw["baseUrl"]
+				delete(raw, "baseUrl")
+				return nil
+			},
+		})
+
+		err := ParseYAML([]byte("baseUrl: https://example.com\n"), &config, WithStrict(true), strictMigrations)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.BaseURL, "https://example.com")
+
+		err = ParseYAML([]byte("version: 2\nbaseURL: https://example.com\n"), &config, WithStrict(true), strictMigrations)
+		tt.AssertNoErr(t, err)
+
+		err = ParseYAML([]byte("version: 2\nbaseURL: https://example.com\nother: 1\n"), &config, WithStrict(true), strictMigrations)
+		tt.AssertErrContains(t, err, "unknown keys: 'other'")
+	})
+
+	t.Run("should write the migrated file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.yaml")
+		err := os.WriteFile(path, []byte("baseUrl: https://example.com\ndbHost: fakeHost\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config Config
+		err = ParseYAMLFile(path, &config, WithMigrations("version", migrations), WithMigratedFile(path))
+		tt.AssertNoErr(t, err)
+
+		migrated, err := os.ReadFile(path)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, string(migrated), `baseURL: https://example.com
+database:
+  host: fakeHost
+version: 3
+`)
+
+		var migratedConfig Config
+		err = ParseYAMLFile(path, &migratedConfig)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, migratedConfig, config)
+
+		// Invalid configs are not written:
+		invalidPath := filepath.Join(dir, "invalid.json")
+		outputPath := filepath.Join(dir, "migrated.json")
+		err = os.WriteFile(invalidPath, []byte(`{"dbHost": "fakeHost"}`), 0o644)
+		tt.AssertNoErr(t, err)
+
+		err = ParseJSONFile(invalidPath, &config, WithMigrations("version", migrations), WithMigratedFile(outputPath))
+		tt.AssertErrContains(t, err, "missing required field 'baseURL'")
+
+		_, err = os.Stat(outputPath)
+		tt.AssertEqual(t, os.IsNotExist(err), true)
+	})
+}
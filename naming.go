@@ -0,0 +1,77 @@
+package kparse
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NamingStrategy derives the key of a struct field from its Go name,
+// it is used for fields with no key on their tags, see WithNamingStrategy.
+type NamingStrategy func(fieldName string) string
+
+// SnakeCase converts Go field names such as "MaxRetries" into "max_retries".
+func SnakeCase(fieldName string) string {
+	return strings.ToLower(strings.Join(splitWords(fieldName), "_"))
+}
+
+// KebabCase converts Go field names such as "MaxRetries" into "max-retries".
+func KebabCase(fieldName string) string {
+	return strings.ToLower(strings.Join(splitWords(fieldName), "-"))
+}
+
+// CamelCase converts Go field names such as "MaxRetries" into "maxRetries",
+// acronyms are treated as words, so "HTTPServer" becomes "httpServer".
+func CamelCase(fieldName string) string {
+	words := splitWords(fieldName)
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if i > 0 {
+			// The initial might take more than one byte, e.g. "É":
+			initial, size := utf8.DecodeRuneInString(word)
+			word = string(unicode.ToUpper(initial)) + word[size:]
+		}
+		words[i] = word
+	}
+	return strings.Join(words, "")
+}
+
+// splitWords splits a Go identifier into words, keeping acronyms
+// together, e.g. "HTTPServerID2" becomes ["HTTP", "Server", "ID2"].
+func splitWords(name string) []string {
+	runes := []rune(name)
+
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, curr := runes[i-1], runes[i]
+
+		lowerToUpper := !unicode.IsUpper(prev) && unicode.IsUpper(curr)
+		acronymEnd := unicode.IsUpper(prev) && unicode.IsUpper(curr) &&
+			i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		underscore := curr == '_'
+
+		if lowerToUpper || acronymEnd || underscore {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i
+			if underscore {
+				start++
+			}
+		}
+	}
+
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// normalizeKey is used for loose key matching, where keys such as
+// "maxRetries", "max_retries" and "Max-Retries" are considered equal.
+func normalizeKey(key string) string {
+	key = strings.ReplaceAll(key, "_", "")
+	key = strings.ReplaceAll(key, "-", "")
+	return strings.ToLower(key)
+}
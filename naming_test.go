@@ -0,0 +1,88 @@
+package kparse
+
+import (
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestNamingStrategies(t *testing.T) {
+	tests := []struct {
+		fieldName     string
+		expectedSnake string
+		expectedKebab string
+		expectedCamel string
+	}{
+		{"MaxRetries", "max_retries", "max-retries", "maxRetries"},
+		{"Host", "host", "host", "host"},
+		{"HTTPServer", "http_server", "http-server", "httpServer"},
+		{"UserID", "user_id", "user-id", "userId"},
+		{"Retries2", "retries2", "retries2", "retries2"},
+		{"Max_Retries", "max_retries", "max-retries", "maxRetries"},
+		{"MaxÉtat", "max_état", "max-état", "maxÉtat"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fieldName, func(t *testing.T) {
+			tt.AssertEqual(t, SnakeCase(test.fieldName), test.expectedSnake)
+			tt.AssertEqual(t, KebabCase(test.fieldName), test.expectedKebab)
+			tt.AssertEqual(t, CamelCase(test.fieldName), test.expectedCamel)
+		})
+	}
+}
+
+func TestKeyMatching(t *testing.T) {
+	type Config struct {
+		MaxRetries int `yaml:"maxRetries"`
+		Timeout    int `yaml:"timeout"`
+	}
+
+	t.Run("should match keys ignoring case, underscores and dashes", func(t *testing.T) {
+		for _, input := range []string{"max_retries: 3", "MaxRetries: 3", "max-retries: 3", "MAX_RETRIES: 3"} {
+			var config Config
+			err := ParseYAML([]byte(input), &config, WithLooseKeys(true), WithStrict(true))
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, config.MaxRetries, 3, input)
+		}
+	})
+
+	t.Run("should derive the keys of untagged fields", func(t *testing.T) {
+		var config struct {
+			MaxRetries int
+			HTTPServer struct {
+				ReadTimeout int `default:"30"`
+			}
+			Tagged  string `yaml:"customKey"`
+			Ignored string `yaml:"-"`
+		}
+
+		input := []byte(`max_retries: 3
+http_server:
+  read_timeout: 10
+customKey: foo
+ignored: bar
+`)
+		err := ParseYAML(input, &config, WithNamingStrategy(SnakeCase))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.MaxRetries, 3)
+		tt.AssertEqual(t, config.HTTPServer.ReadTimeout, 10)
+		tt.AssertEqual(t, config.Tagged, "foo")
+		tt.AssertEqual(t, config.Ignored, "")
+
+		// Without a strategy fields without tags are ignored:
+		config.MaxRetries = 0
+		err = ParseYAML(input, &config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.MaxRetries, 0)
+	})
+
+	t.Run("should combine naming strategies with loose keys", func(t *testing.T) {
+		var config struct {
+			MaxRetries int
+		}
+
+		err := ParseJSON([]byte(`{"MaxRetries": 5}`), &config, WithNamingStrategy(CamelCase), WithLooseKeys(true))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.MaxRetries, 5)
+	})
+}
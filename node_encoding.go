@@ -0,0 +1,531 @@
+package kparse
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vingarcia/structi"
+	"gopkg.in/yaml.v3"
+)
+
+// The functions on this file render a *yaml.Node tree in other formats,
+// which allows us to build a single tree (with comments and key order)
+// and output it in any of the formats kparse knows how to write.
+
+func encodeNodeAsYAML(nodes ...*yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	// Each node is written as a separate document:
+	for _, node := range nodes {
+		err := encoder.Encode(node)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeNodeAsJSON writes the node as indented JSON preserving the
+// order of the keys, comments are discarded since JSON has no comments.
+func encodeNodeAsJSON(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	err := writeJSONNode(&buf, node, "")
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+func writeJSONNode(buf *bytes.Buffer, node *yaml.Node, indent string) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeJSONNode(buf, node.Content[0], indent)
+
+	case yaml.AliasNode:
+		return writeJSONNode(buf, node.Alias, indent)
+
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+
+		buf.WriteString("{\n")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			buf.WriteString(indent + "  ")
+			err := writeJSONScalar(buf, node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			buf.WriteString(": ")
+
+			err = writeJSONNode(buf, node.Content[i+1], indent+"  ")
+			if err != nil {
+				return err
+			}
+
+			if i+2 < len(node.Content) {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "}")
+		return nil
+
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+
+		buf.WriteString("[\n")
+		for i, item := range node.Content {
+			buf.WriteString(indent + "  ")
+			err := writeJSONNode(buf, item, indent+"  ")
+			if err != nil {
+				return err
+			}
+
+			if i+1 < len(node.Content) {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "]")
+		return nil
+	}
+
+	var value any
+	err := node.Decode(&value)
+	if err != nil {
+		return err
+	}
+
+	return writeJSONScalar(buf, value)
+}
+
+func writeJSONScalar(buf *bytes.Buffer, value any) error {
+	var scalar bytes.Buffer
+	encoder := json.NewEncoder(&scalar)
+	encoder.SetEscapeHTML(false)
+
+	err := encoder.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	buf.Write(bytes.TrimRight(scalar.Bytes(), "\n"))
+	return nil
+}
+
+// encodeNodeAsTOML writes a mapping node as a TOML document,
+// the head and line comments of each key are kept as TOML comments.
+func encodeNodeAsTOML(node *yaml.Node) ([]byte, error) {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("TOML documents must be tables, but got a YAML node of kind %v", node.Kind)
+	}
+
+	var buf bytes.Buffer
+	err := writeTOMLTable(&buf, nil, node)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.FootComment != "" {
+		buf.WriteByte('\n')
+		writeTOMLComment(&buf, node.FootComment)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeTOMLTable(buf *bytes.Buffer, path []string, node *yaml.Node) error {
+	// TOML requires the simple keys of a table to be written
+	// before any of its sub-tables, so we do it in two passes:
+	var tables []int
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if isTOMLTable(value) {
+			tables = append(tables, i)
+			continue
+		}
+
+		if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+			// TOML has no null values, so we can only omit them:
+			continue
+		}
+
+		writeTOMLComment(buf, key.HeadComment)
+		buf.WriteString(tomlKey(key.Value) + " = ")
+		err := writeTOMLValue(buf, value)
+		if err != nil {
+			return err
+		}
+		writeTOMLLineComment(buf, key.LineComment)
+		buf.WriteByte('\n')
+	}
+
+	for _, i := range tables {
+		key, value := node.Content[i], node.Content[i+1]
+		tablePath := append(append([]string{}, path...), tomlKey(key.Value))
+
+		if value.Kind == yaml.MappingNode {
+			buf.WriteByte('\n')
+			writeTOMLComment(buf, key.HeadComment)
+			buf.WriteString("[" + strings.Join(tablePath, ".") + "]")
+			writeTOMLLineComment(buf, key.LineComment)
+			buf.WriteByte('\n')
+			err := writeTOMLTable(buf, tablePath, value)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Sequences of mappings are written as arrays of tables,
+		// the comments of each item are written on its header:
+		for _, item := range value.Content {
+			comment := key.HeadComment
+			if item.HeadComment != "" {
+				comment = item.HeadComment
+			}
+
+			buf.WriteByte('\n')
+			writeTOMLComment(buf, comment)
+			buf.WriteString("[[" + strings.Join(tablePath, ".") + "]]")
+			writeTOMLLineComment(buf, item.LineComment)
+			buf.WriteByte('\n')
+			err := writeTOMLTable(buf, tablePath, item)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func isTOMLTable(node *yaml.Node) bool {
+	if node.Kind == yaml.MappingNode {
+		return true
+	}
+
+	if node.Kind != yaml.SequenceNode || len(node.Content) == 0 {
+		return false
+	}
+
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return true
+}
+
+func writeTOMLValue(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return writeTOMLValue(buf, node.Alias)
+
+	case yaml.MappingNode:
+		buf.WriteString("{ ")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(tomlKey(node.Content[i].Value) + " = ")
+			err := writeTOMLValue(buf, node.Content[i+1])
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteString(" }")
+		return nil
+
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			err := writeTOMLValue(buf, item)
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+
+	if node.Tag == "!!timestamp" {
+		// Dates are written as they are, since TOML also uses RFC 3339:
+		buf.WriteString(node.Value)
+		return nil
+	}
+
+	var value any
+	err := node.Decode(&value)
+	if err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case string:
+		buf.WriteString(tomlString(v))
+	case float64:
+		buf.WriteString(tomlFloat(v))
+	case nil:
+		return fmt.Errorf("TOML does not support null values")
+	default:
+		fmt.Fprint(buf, v)
+	}
+
+	return nil
+}
+
+func writeTOMLComment(buf *bytes.Buffer, comment string) {
+	if comment == "" {
+		return
+	}
+
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		buf.WriteString("# " + line + "\n")
+	}
+}
+
+func writeTOMLLineComment(buf *bytes.Buffer, comment string) {
+	if comment == "" {
+		return
+	}
+
+	buf.WriteString(" # " + strings.TrimSpace(strings.TrimPrefix(comment, "#")))
+}
+
+func tomlFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		// Otherwise TOML would read it as an integer:
+		s += ".0"
+	}
+	return s
+}
+
+var bareTOMLKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(key string) string {
+	if bareTOMLKey.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// valueNode builds a *yaml.Node tree from a Go value using the `tagName`
+// tag for the keys of struct fields, fields without this tag are ignored.
+//
+// If redact is true the values of secret fields are replaced by "******",
+// otherwise the values wrapped by Secret types are written as they are.
+func valueNode(tagName string, v reflect.Value, redact bool) (*yaml.Node, error) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+		}
+		return valueNode(tagName, v.Elem(), redact)
+
+	case reflect.Struct:
+		if isSecretType(v.Type()) {
+			if redact {
+				return redactedNode(), nil
+			}
+
+			if !v.CanAddr() {
+				// e.g. values from maps are not addressable:
+				ptr := reflect.New(v.Type())
+				ptr.Elem().Set(v)
+				v = ptr.Elem()
+			}
+
+			secret := v.Addr().Interface().(secretValue)
+			return valueNode(tagName, reflect.ValueOf(secret.secretValuePtr()).Elem(), redact)
+		}
+		if hasMarshaler(v.Type()) {
+			return marshalerNode(v)
+		}
+
+		info, err := structi.GetStructInfo(v.Type())
+		if err != nil {
+			return nil, err
+		}
+
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, field := range info.Fields {
+			key := keyFromTag(field.Tags[tagName])
+			if key == "" {
+				continue
+			}
+
+			var value *yaml.Node
+			if redact && isSecretField(field.Type, field.Tags) {
+				value = redactedNode()
+			} else {
+				value, err = valueNode(tagName, v.FieldByName(field.Name), redact)
+				if err != nil {
+					return nil, fmt.Errorf("error encoding field '%s': %w", field.Name, err)
+				}
+			}
+
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+				value,
+			)
+		}
+		return node, nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+		}
+		if hasMarshaler(v.Type()) {
+			return marshalerNode(v)
+		}
+
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i := 0; i < v.Len(); i++ {
+			item, err := valueNode(tagName, v.Index(i), redact)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, item)
+		}
+		return node, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+		}
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range keys {
+			keyNode, err := valueNode(tagName, key, redact)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := valueNode(tagName, v.MapIndex(key), redact)
+			if err != nil {
+				return nil, err
+			}
+
+			node.Content = append(node.Content, keyNode, value)
+		}
+		return node, nil
+	}
+
+	var node yaml.Node
+	err := node.Encode(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return withScalarTag(&node, v.Type()), nil
+}
+
+var (
+	yamlMarshalerType = reflect.TypeOf((*yaml.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// hasMarshaler reports if t encodes itself, e.g. time.Time or net.IP,
+// in which case its fields or items must not be written one by one.
+func hasMarshaler(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return pt.Implements(yamlMarshalerType) || pt.Implements(textMarshalerType)
+}
+
+// marshalerNode encodes a value using its own marshaler.
+func marshalerNode(v reflect.Value) (*yaml.Node, error) {
+	if !v.CanAddr() {
+		// The marshaler might be declared on the pointer type:
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr.Elem()
+	}
+
+	var node yaml.Node
+	err := node.Encode(v.Addr().Interface())
+	if err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+func redactedNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: redacted}
+}
@@ -0,0 +1,196 @@
+package kparse
+
+import (
+	"io/fs"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"sync/atomic"
+)
+
+// Option customizes the behavior of a Parser when passed to NewParser,
+// or of a single call when passed to one of the Parse functions.
+type Option func(*parseOptions)
+
+type parseOptions struct {
+	sourceName string
+	origins    *Origins
+
+	tagName         string
+	validateTagName string
+	defaultTagName  string
+
+	strict          bool
+	caseInsensitive bool
+	looseKeys       bool
+	namingStrategy  NamingStrategy
+
+	validators map[validatorFactoryMapKey]ValidatorFactory
+	// validatorsID changes every time a validator is added,
+	// so parsers know when they can't use their validator cache
+	validatorsID int64
+
+	warnings *[]Warning
+	logger   *slog.Logger
+
+	profileEnvVar string
+
+	versionKey       string
+	migrations       map[int]Migration
+	migratedFilePath string
+
+	includes        bool
+	includeMaxDepth int
+	includeRoot     string
+	includeFS       fs.FS
+
+	setenv bool
+
+	listMergeStrategy ListMergeStrategy
+	dirPattern        string
+
+	decodeHooks map[reflect.Type]func(LazyDecoder) (any, error)
+	sources     []Source
+}
+
+// WithSourceName sets the name used for describing where the
+// parsed values came from, e.g. on the origins recorded by
+// WithOrigins. The file functions use the file path by default.
+func WithSourceName(name string) Option {
+	return func(opts *parseOptions) {
+		opts.sourceName = name
+	}
+}
+
+// WithOrigins records where each field got its value from,
+// the map pointed by origins is replaced on every call.
+//
+// It is ignored by NewParser, so it must be passed to each call.
+func WithOrigins(origins *Origins) Option {
+	return func(opts *parseOptions) {
+		opts.origins = origins
+	}
+}
+
+// WithTagName sets the struct tag used for reading the keys,
+// by default it is the name of the format, e.g. "yaml" or "json".
+func WithTagName(name string) Option {
+	return func(opts *parseOptions) {
+		opts.tagName = name
+	}
+}
+
+// WithValidateTagName sets the struct tag used
+// for the validations instead of `validate`.
+func WithValidateTagName(name string) Option {
+	return func(opts *parseOptions) {
+		opts.validateTagName = name
+	}
+}
+
+// WithDefaultTagName sets the struct tag used
+// for the default values instead of `default`.
+func WithDefaultTagName(name string) Option {
+	return func(opts *parseOptions) {
+		opts.defaultTagName = name
+	}
+}
+
+// WithStrict makes keys that don't match any struct field an error,
+// which helps finding typos, by default these keys are ignored.
+func WithStrict(strict bool) Option {
+	return func(opts *parseOptions) {
+		opts.strict = strict
+	}
+}
+
+// WithCaseInsensitiveKeys makes the keys of the input match the
+// keys on the struct tags regardless of their case, exact matches
+// are still preferred when both are present.
+func WithCaseInsensitiveKeys(caseInsensitive bool) Option {
+	return func(opts *parseOptions) {
+		opts.caseInsensitive = caseInsensitive
+	}
+}
+
+// WithLooseKeys makes the keys of the input match the keys on
+// the struct tags ignoring their case, underscores and dashes, so
+// "maxRetries" matches "max_retries", "MaxRetries" and "max-retries".
+func WithLooseKeys(loose bool) Option {
+	return func(opts *parseOptions) {
+		opts.looseKeys = loose
+	}
+}
+
+// WithNamingStrategy derives the keys of fields with no key on their
+// tags from their Go names, which are otherwise ignored, e.g.:
+//
+//	kparse.WithNamingStrategy(kparse.SnakeCase)
+//
+// Fields can still be ignored with a "-" key, e.g. `yaml:"-"`.
+func WithNamingStrategy(strategy NamingStrategy) Option {
+	return func(opts *parseOptions) {
+		opts.namingStrategy = strategy
+	}
+}
+
+var lastValidatorsID atomic.Int64
+
+// WithValidator adds a custom validation that can be used on the
+// validate tags, its name must contain only letters, and if no kinds
+// are passed the validation is used for fields of any kind.
+//
+// Custom validations take precedence over the built-in ones.
+func WithValidator(name string, factory ValidatorFactory, kinds ...reflect.Kind) Option {
+	if len(kinds) == 0 {
+		kinds = []reflect.Kind{reflect.Invalid}
+	}
+
+	return func(opts *parseOptions) {
+		// Cloned so options applied on a single call never change the Parser:
+		opts.validators = maps.Clone(opts.validators)
+		if opts.validators == nil {
+			opts.validators = map[validatorFactoryMapKey]ValidatorFactory{}
+		}
+
+		for _, kind := range kinds {
+			opts.validators[validatorFactoryMapKey{name, kind}] = factory
+		}
+		opts.validatorsID = lastValidatorsID.Add(1)
+	}
+}
+
+// WithDecodeHook sets how fields of type T are decoded, which is useful
+// for types that can't be decoded from the config files on their own:
+//
+//	kparse.WithDecodeHook(func(value kparse.LazyDecoder) (*url.URL, error) {
+//		var rawURL string
+//		err := value.Decode(&rawURL)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return url.Parse(rawURL)
+//	})
+//
+// The hooks are not called for missing fields, which still use their default values.
+func WithDecodeHook[T any](hook func(value LazyDecoder) (T, error)) Option {
+	return func(opts *parseOptions) {
+		opts.decodeHooks = maps.Clone(opts.decodeHooks)
+		if opts.decodeHooks == nil {
+			opts.decodeHooks = map[reflect.Type]func(LazyDecoder) (any, error){}
+		}
+
+		opts.decodeHooks[reflect.TypeOf((*T)(nil)).Elem()] = func(value LazyDecoder) (any, error) {
+			return hook(value)
+		}
+	}
+}
+
+// WithSources adds sources to be loaded by Parser.Load, the
+// values of each source override the values of the previous ones.
+func WithSources(sources ...Source) Option {
+	return func(opts *parseOptions) {
+		opts.sources = append(slices.Clip(opts.sources), sources...)
+	}
+}
@@ -0,0 +1,176 @@
+package kparse
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Origin describes where the value of a field came from.
+type Origin struct {
+	// Source is the name of the file or source containing the value,
+	// it is empty if the value was parsed from a reader or slice of
+	// bytes without using the WithSourceName option.
+	Source string
+
+	// Line and Column are only available for YAML sources.
+	Line   int
+	Column int
+
+	// EnvVar is set when the value was loaded from an environment variable.
+	EnvVar string
+
+	// Default is true if the value came from the `default` tag.
+	Default bool
+
+	// Secret is true if the value was treated as secret, which is
+	// also the case for any value loaded from a secret reference.
+	Secret bool
+}
+
+// String describes the origin in the formats: "default",
+// "config.yaml:12:3", "config.yaml", "line 12, column 3" or "env NAME".
+func (o Origin) String() string {
+	if o.Default {
+		return "default"
+	}
+
+	location := o.Source
+	if o.Line > 0 {
+		if location == "" {
+			location = fmt.Sprintf("line %d, column %d", o.Line, o.Column)
+		} else {
+			location += ":" + strconv.Itoa(o.Line) + ":" + strconv.Itoa(o.Column)
+		}
+	}
+
+	if o.EnvVar == "" {
+		return location
+	}
+	if location == "" {
+		return "env " + o.EnvVar
+	}
+	return location + " (env " + o.EnvVar + ")"
+}
+
+// Origins maps the key path of each field, e.g. "address.street"
+// or "users[0].name", to the origin of its value.
+//
+// Only the fields that got a value are recorded and for nested
+// structs and slices of structs only their fields are recorded.
+type Origins map[string]Origin
+
+// originProbe is used for reading the source and position of YAML values,
+// the JSON decoder doesn't expose positions so JSON values are just ignored.
+type originProbe struct {
+	source string
+	line   int
+	column int
+	envVar string
+}
+
+func (p *originProbe) UnmarshalYAML(node *yaml.Node) error {
+	p.line = node.Line
+	p.column = node.Column
+	return nil
+}
+
+func (p *originProbe) UnmarshalJSON([]byte) error {
+	return nil
+}
+
+func originOf(source string, value LazyDecoder) Origin {
+	var probe originProbe
+	_ = value.Decode(&probe)
+
+	if probe.envVar != "" && probe.line == 0 {
+		// Values read straight from the environment have no source file:
+		source = ""
+	}
+
+	return Origin{
+		// Values from merged sources have their own source names:
+		Source: cmp.Or(probe.source, source),
+		Line:   probe.line,
+		Column: probe.column,
+		EnvVar: probe.envVar,
+	}
+}
+
+// envVarReference returns the name of the environment variable
+// if the value is a `${env:NAME}` secret reference.
+func envVarReference(value LazyDecoder) string {
+	var raw string
+	if value.Decode(&raw) != nil {
+		return ""
+	}
+
+	match := secretReferenceRegex.FindStringSubmatch(raw)
+	if match == nil || match[1] != "env" {
+		return ""
+	}
+
+	return match[2]
+}
+
+// DumpWithOrigins works like Dump but also adds a comment to each
+// value describing its origin, as recorded by the WithOrigins option:
+//
+//	var origins kparse.Origins
+//	err := kparse.ParseYAMLFile("config.yaml", &config, kparse.WithOrigins(&origins))
+//	...
+//	dump, err := kparse.DumpWithOrigins(&config, "yaml", origins)
+//
+// Values that were loaded from secret references are also redacted.
+func DumpWithOrigins(structPtr any, tagName string, origins Origins) (string, error) {
+	v := reflect.ValueOf(structPtr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return "", fmt.Errorf("expected non-nil struct pointer but got: %T", structPtr)
+	}
+
+	node, err := valueNode(tagName, v.Elem(), true)
+	if err != nil {
+		return "", err
+	}
+
+	addOriginComments(node, "", origins)
+
+	output, err := encodeNodeAsYAML(node)
+	return string(output), err
+}
+
+func addOriginComments(node *yaml.Node, path string, origins Origins) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			valuePath := joinKeyPath(path, key.Value)
+
+			origin, found := origins[valuePath]
+			if !found {
+				addOriginComments(value, valuePath, origins)
+				continue
+			}
+
+			if origin.Secret {
+				value = redactedNode()
+				node.Content[i+1] = value
+			}
+
+			if value.Kind == yaml.ScalarNode {
+				value.LineComment = origin.String()
+			} else {
+				// Comments on collections are written next to their keys:
+				key.LineComment = origin.String()
+			}
+		}
+
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			addOriginComments(item, fmt.Sprintf("%s[%d]", path, i), origins)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package kparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestOrigins(t *testing.T) {
+	type Config struct {
+		MaxRetries int      `yaml:"maxRetries" json:"maxRetries" default:"3"`
+		Host       string   `yaml:"host" json:"host"`
+		Password   string   `yaml:"password" json:"password" secret:"true"`
+		Tags       []string `yaml:"tags" json:"tags"`
+		Database   struct {
+			Port int    `yaml:"port" json:"port" default:"5432"`
+			Name string `yaml:"name" json:"name"`
+		} `yaml:"database" json:"database"`
+		Users []struct {
+			Name string `yaml:"name" json:"name"`
+		} `yaml:"users" json:"users"`
+		Missing string `yaml:"missing" json:"missing"`
+	}
+
+	t.Run("should record the origins of yaml values", func(t *testing.T) {
+		t.Setenv("KPARSE_TEST_PASSWORD", "fakePassword")
+
+		var config Config
+		var origins Origins
+		err := ParseYAML([]byte(`host: fakeHost
+password: ${env:KPARSE_TEST_PASSWORD}
+tags:
+  - foo
+  - bar
+database:
+  name: fakeName
+users:
+  - name: fakeUser1
+  - name: fakeUser2
+`), &config, WithOrigins(&origins), WithSourceName("config.yaml"))
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, origins, Origins{
+			"maxRetries":    {Default: true},
+			"host":          {Source: "config.yaml", Line: 1, Column: 7},
+			"password":      {Source: "config.yaml", Line: 2, Column: 11, EnvVar: "KPARSE_TEST_PASSWORD", Secret: true},
+			"tags":          {Source: "config.yaml", Line: 4, Column: 3},
+			"database.port": {Default: true},
+			"database.name": {Source: "config.yaml", Line: 7, Column: 9},
+			"users[0].name": {Source: "config.yaml", Line: 9, Column: 11},
+			"users[1].name": {Source: "config.yaml", Line: 10, Column: 11},
+		})
+
+		output, err := DumpWithOrigins(&config, "yaml", origins)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, output, `maxRetries: 3 # default
+host: fakeHost # config.yaml:1:7
+password: '******' # config.yaml:2:11 (env KPARSE_TEST_PASSWORD)
+tags: # config.yaml:4:3
+  - foo
+  - bar
+database:
+  port: 5432 # default
+  name: fakeName # config.yaml:7:9
+users:
+  - name: fakeUser1 # config.yaml:9:11
+  - name: fakeUser2 # config.yaml:10:11
+missing: ""
+`)
+	})
+
+	t.Run("should use the file path as the source name", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(path, []byte("host: fakeHost\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config Config
+		var origins Origins
+		err = ParseYAMLFile(path, &config, WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, origins["host"], Origin{Source: path, Line: 1, Column: 7})
+	})
+
+	t.Run("should record secret files as the source", func(t *testing.T) {
+		var config struct {
+			Password string `yaml:"password" secretFile:"testdata/secret_password"`
+		}
+		var origins Origins
+		err := ParseYAML([]byte(`{}`), &config, WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, origins, Origins{
+			"password": {Source: "testdata/secret_password", Secret: true},
+		})
+	})
+
+	t.Run("should record only the source name for json values", func(t *testing.T) {
+		var config Config
+		var origins Origins
+		err := ParseJSON([]byte(`{"host": "fakeHost"}`), &config, WithOrigins(&origins), WithSourceName("config.json"))
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, origins["host"], Origin{Source: "config.json"})
+		tt.AssertEqual(t, origins["host"].String(), "config.json")
+	})
+}
+
+func TestOriginString(t *testing.T) {
+	tests := []struct {
+		desc     string
+		origin   Origin
+		expected string
+	}{
+		{
+			desc:     "should describe default values",
+			origin:   Origin{Default: true},
+			expected: "default",
+		},
+		{
+			desc:     "should describe positions without a source name",
+			origin:   Origin{Line: 3, Column: 5},
+			expected: "line 3, column 5",
+		},
+		{
+			desc:     "should describe values from env vars",
+			origin:   Origin{EnvVar: "MAX_RETRIES"},
+			expected: "env MAX_RETRIES",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			tt.AssertEqual(t, test.origin.String(), test.expected)
+		})
+	}
+}
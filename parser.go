@@ -0,0 +1,137 @@
+package kparse
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Parser holds the settings used for parsing config files, different
+// parts of a program can use different parsers without affecting each
+// other, e.g.:
+//
+//	parser := kparse.NewParser(
+//		kparse.WithTagName("config"),
+//		kparse.WithStrict(true),
+//	)
+//
+//	err := parser.ParseYAMLFile("config.yaml", &config)
+//
+// The top-level Parse functions use a Parser with the default settings.
+//
+// A Parser is safe for concurrent use.
+type Parser struct {
+	options parseOptions
+
+	validatorCache sync.Map
+}
+
+var defaultParser = NewParser()
+
+// NewParser creates a Parser with the given options, they can
+// still be overridden by the options passed to each call.
+//
+// WithWarnings and WithOrigins collect the results of a single call,
+// so they are ignored by NewParser and must be passed to each call,
+// otherwise concurrent calls would share them.
+func NewParser(opts ...Option) *Parser {
+	p := &Parser{}
+	for _, opt := range opts {
+		opt(&p.options)
+	}
+
+	p.options.warnings = nil
+	p.options.origins = nil
+
+	return p
+}
+
+// Load parses the sources set with the WithSources option into
+// the target, the values of each source override the values of
+// the previous ones and nested maps are merged. Lists are merged
+// according to WithListMergeStrategy and the `merge` tags.
+//
+// The keys are read from the `yaml` tag unless WithTagName is used.
+func (p *Parser) Load(target any, opts ...Option) error {
+	state := p.newParseState("yaml", opts)
+	if len(state.options.sources) == 0 {
+		return fmt.Errorf("no sources to load, use the WithSources option to set them")
+	}
+
+	merger := newLayerMerger(state, target)
+
+	var merged LazyDecoder
+	for _, source := range state.options.sources {
+		value, err := source.Load()
+		if err != nil {
+			return fmt.Errorf("error loading source '%s': %w", source.Name(), err)
+		}
+
+		if state.options.includes {
+			// Each source includes files relative to its own path:
+			value, err = state.resolveIncludes(value, source.Name(), nil)
+			if err != nil {
+				return fmt.Errorf("error loading source '%s': %w", source.Name(), err)
+			}
+		}
+
+		merged, err = merger.mergeLayer(merged, withSource(source.Name(), value), source.Name())
+		if err != nil {
+			return fmt.Errorf("error loading source '%s': %w", source.Name(), err)
+		}
+	}
+
+	state.includesResolved = true
+	return state.parseRoot(target, merged)
+}
+
+// newParseState prepares a parse of the given format, e.g. "yaml",
+// which is also the default tag name for reading the keys.
+func (p *Parser) newParseState(format string, opts []Option) *parseState {
+	options := p.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	state := &parseState{
+		options:     options,
+		format:      format,
+		tagName:     cmp.Or(options.tagName, format),
+		validateTag: cmp.Or(options.validateTagName, "validate"),
+		defaultTag:  cmp.Or(options.defaultTagName, "default"),
+		cache:       &p.validatorCache,
+	}
+
+	if options.validatorsID != p.options.validatorsID {
+		// The validators were changed for this call only:
+		state.cache = &sync.Map{}
+	}
+
+	if options.warnings != nil {
+		*options.warnings = nil
+	}
+
+	if options.origins != nil {
+		*options.origins = Origins{}
+		state.origins = *options.origins
+	}
+
+	return state
+}
+
+// validatorFactory returns the custom validators of the
+// parser if they exist or one of the built-in validators.
+func (s *parseState) validatorFactory(name string, kind reflect.Kind) (ValidatorFactory, bool) {
+	if factory, found := s.options.validators[validatorFactoryMapKey{name, kind}]; found {
+		return factory, true
+	}
+
+	// Custom validators registered for all kinds:
+	if factory, found := s.options.validators[validatorFactoryMapKey{name, reflect.Invalid}]; found {
+		return factory, true
+	}
+
+	factory, found := validatorFactoryMap[validatorFactoryMapKey{name, kind}]
+	return factory, found
+}
@@ -0,0 +1,234 @@
+package kparse
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestParser(t *testing.T) {
+	t.Run("should use the configured tag names", func(t *testing.T) {
+		parser := NewParser(
+			WithTagName("config"),
+			WithValidateTagName("check"),
+			WithDefaultTagName("fallback"),
+		)
+
+		var config struct {
+			Host       string `config:"host" check:"required"`
+			MaxRetries int    `config:"maxRetries" fallback:"3" check:"<=10"`
+		}
+		err := parser.ParseYAML([]byte(`host: fakeHost`), &config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.Host, "fakeHost")
+		tt.AssertEqual(t, config.MaxRetries, 3)
+
+		err = parser.ParseJSON([]byte(`{"maxRetries": 11}`), &config)
+		tt.AssertErrContains(t, err, "missing required field 'host'")
+
+		err = parser.ParseJSON([]byte(`{"host": "fakeHost", "maxRetries": 11}`), &config)
+		tt.AssertErrContains(t, err, "MaxRetries", "<= 10")
+	})
+
+	t.Run("should report unknown keys on strict mode", func(t *testing.T) {
+		var config struct {
+			Host    string `yaml:"host"`
+			Address struct {
+				Street string `yaml:"street"`
+			} `yaml:"address"`
+			Users []struct {
+				Name string `yaml:"name"`
+			} `yaml:"users"`
+		}
+
+		input := []byte(`host: fakeHost
+hots: typo
+address:
+  street: fakeStreet
+users:
+  - name: fakeUser
+    nmae: typo
+`)
+		err := ParseYAML(input, &config)
+		tt.AssertNoErr(t, err)
+
+		err = NewParser(WithStrict(true)).ParseYAML(input, &config)
+		tt.AssertErrContains(t, err, "unknown keys: 'users[0].nmae'")
+
+		err = NewParser(WithStrict(true)).ParseYAML([]byte("host: fakeHost\nhots: typo\nhsot: typo\n"), &config)
+		tt.AssertErrContains(t, err, "unknown keys: 'hots', 'hsot'")
+
+		err = NewParser(WithStrict(true)).ParseYAML([]byte("address:\n  strete: typo\n"), &config)
+		tt.AssertErrContains(t, err, "unknown keys: 'address.strete'")
+
+		// Options passed to each call override the options of the Parser:
+		err = NewParser(WithStrict(true)).ParseYAML(input, &config, WithStrict(false))
+		tt.AssertNoErr(t, err)
+	})
+
+	t.Run("should match keys ignoring their case", func(t *testing.T) {
+		var config struct {
+			MaxRetries int `yaml:"maxRetries"`
+			Timeout    int `yaml:"timeout"`
+		}
+
+		parser := NewParser(WithCaseInsensitiveKeys(true))
+		err := parser.ParseYAML([]byte("MaxRetries: 3\nTIMEOUT: 10\ntimeout: 20\n"), &config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.MaxRetries, 3)
+		tt.AssertEqual(t, config.Timeout, 20)
+
+		err = parser.ParseYAML([]byte("MaxRetries: 3\nTIMEOUT: 10\n"), &config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.Timeout, 10)
+	})
+
+	t.Run("should use custom validators", func(t *testing.T) {
+		prefixValidator := func(fieldName string, rule string) (Validator, error) {
+			prefix := strings.TrimPrefix(rule, "=")
+			return func(value any) error {
+				if !strings.HasPrefix(*value.(*string), prefix) {
+					return errors.New("field " + fieldName + " should start with " + prefix)
+				}
+				return nil
+			}, nil
+		}
+
+		type Config struct {
+			URL string `yaml:"url" validate:"prefix=https://"`
+		}
+
+		parser := NewParser(WithValidator("prefix", prefixValidator, reflect.String))
+
+		var config Config
+		err := parser.ParseYAML([]byte(`url: https://example.com`), &config)
+		tt.AssertNoErr(t, err)
+
+		err = parser.ParseYAML([]byte(`url: http://example.com`), &config)
+		tt.AssertErrContains(t, err, "URL should start with https://")
+
+		// Other parsers are not affected:
+		err = ParseYAML([]byte(`url: https://example.com`), &config)
+		tt.AssertErrContains(t, err, "unrecognized validation exp: 'prefix=https://'")
+
+		// Validators passed to a single call don't change the Parser:
+		acceptAll := func(fieldName string, rule string) (Validator, error) {
+			return func(value any) error { return nil }, nil
+		}
+		err = parser.ParseYAML([]byte(`url: http://example.com`), &config, WithValidator("prefix", acceptAll, reflect.String))
+		tt.AssertNoErr(t, err)
+
+		err = parser.ParseYAML([]byte(`url: http://example.com`), &config)
+		tt.AssertErrContains(t, err, "URL should start with https://")
+	})
+
+	t.Run("should use decode hooks", func(t *testing.T) {
+		var config struct {
+			Endpoint *url.URL `yaml:"endpoint"`
+			Mirror   url.URL  `yaml:"mirror"`
+		}
+
+		parser := NewParser(
+			WithDecodeHook(func(value LazyDecoder) (*url.URL, error) {
+				var rawURL string
+				err := value.Decode(&rawURL)
+				if err != nil {
+					return nil, err
+				}
+				return url.Parse(rawURL)
+			}),
+			WithDecodeHook(func(value LazyDecoder) (url.URL, error) {
+				var rawURL string
+				err := value.Decode(&rawURL)
+				if err != nil {
+					return url.URL{}, err
+				}
+				u, err := url.Parse(rawURL)
+				return *u, err
+			}),
+		)
+
+		err := parser.ParseYAML([]byte("endpoint: https://example.com/api\nmirror: https://mirror.com\n"), &config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.Endpoint.Host, "example.com")
+		tt.AssertEqual(t, config.Endpoint.Path, "/api")
+		tt.AssertEqual(t, config.Mirror.Host, "mirror.com")
+
+		err = parser.ParseYAML([]byte("endpoint: \"https://exa mple.com\"\n"), &config)
+		tt.AssertErrContains(t, err, "invalid character")
+	})
+
+	t.Run("should ignore options that collect the results of a single call", func(t *testing.T) {
+		var config struct {
+			Host string `yaml:"host" aliases:"hostname"`
+		}
+
+		var warnings []Warning
+		var origins Origins
+		parser := NewParser(WithWarnings(&warnings), WithOrigins(&origins))
+		err := parser.ParseYAML([]byte("hostname: fakeHost\n"), &config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, warnings, []Warning(nil))
+		tt.AssertEqual(t, origins, Origins(nil))
+
+		// They are still accepted by each call:
+		err = parser.ParseYAML([]byte("hostname: fakeHost\n"), &config, WithWarnings(&warnings), WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, len(warnings), 1)
+		tt.AssertEqual(t, origins["host"].Line, 1)
+	})
+
+	t.Run("should load and merge the sources", func(t *testing.T) {
+		type Config struct {
+			Host       string            `yaml:"host"`
+			MaxRetries int               `yaml:"maxRetries" default:"3"`
+			Labels     map[string]string `yaml:"labels"`
+			Database   struct {
+				Name string `yaml:"name"`
+				Port int    `yaml:"port"`
+			} `yaml:"database"`
+		}
+
+		path := filepath.Join(t.TempDir(), "base.yaml")
+		err := os.WriteFile(path, []byte(`host: baseHost
+labels:
+  team: core
+database:
+  name: baseName
+  port: 5432
+`), 0o644)
+		tt.AssertNoErr(t, err)
+
+		parser := NewParser(WithSources(
+			FileSource(path),
+			JSONSource("override.json", []byte(`{"labels": {"env": "prod"}, "database": {"port": 5433}}`)),
+		))
+
+		var config Config
+		var origins Origins
+		err = parser.Load(&config, WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, config.Host, "baseHost")
+		tt.AssertEqual(t, config.MaxRetries, 3)
+		tt.AssertEqual(t, config.Labels, map[string]string{"team": "core", "env": "prod"})
+		tt.AssertEqual(t, config.Database.Name, "baseName")
+		tt.AssertEqual(t, config.Database.Port, 5433)
+
+		tt.AssertEqual(t, origins["host"], Origin{Source: path, Line: 1, Column: 7})
+		tt.AssertEqual(t, origins["database.name"], Origin{Source: path, Line: 5, Column: 9})
+		tt.AssertEqual(t, origins["database.port"], Origin{Source: "override.json"})
+
+		err = NewParser().Load(&config)
+		tt.AssertErrContains(t, err, "no sources")
+
+		err = parser.Load(&config, WithSources(FileSource("missing.yaml")))
+		tt.AssertErrContains(t, err, "error loading source 'missing.yaml'")
+	})
+}
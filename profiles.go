@@ -0,0 +1,125 @@
+package kparse
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// profilesKey is the key holding the profiles on config files.
+const profilesKey = "profiles"
+
+// WithProfileEnvVar selects the profile used by ParseWithProfile from an
+// environment variable, when it is set it overrides the profile argument.
+func WithProfileEnvVar(name string) Option {
+	return func(opts *parseOptions) {
+		opts.profileEnvVar = name
+	}
+}
+
+// ParseWithProfile parses a YAML or JSON file with a `profiles` key, e.g.:
+//
+//	maxRetries: 3
+//	database:
+//	  host: localhost
+//
+//	profiles:
+//	  prod:
+//	    database:
+//	      host: db.example.com
+//
+// The chosen profile is merged over the rest of the file before
+// applying the default values and validations, nested maps are merged
+// and any other values replace the base values. If the profile is empty
+// only the base values are used.
+func ParseWithProfile(path string, profile string, target any, opts ...Option) error {
+	return defaultParser.ParseWithProfile(path, profile, target, opts...)
+}
+
+// ParseWithProfile works like the top-level ParseWithProfile using the settings of the Parser.
+func (p *Parser) ParseWithProfile(path string, profile string, target any, opts ...Option) error {
+	format, err := formatFromPath(path)
+	if err != nil {
+		return err
+	}
+
+	opts = append([]Option{WithSourceName(path)}, opts...)
+	state := p.newParseState(format, opts)
+
+	if state.options.profileEnvVar != "" {
+		if envProfile := os.Getenv(state.options.profileEnvVar); envProfile != "" {
+			profile = envProfile
+		}
+	}
+
+	value, err := FileSource(path).Load()
+	if err != nil {
+		return err
+	}
+
+	if state.options.includes {
+		// Resolved first so profiles can also be declared on included files:
+		value, err = state.resolveIncludes(value, path, nil)
+		if err != nil {
+			return err
+		}
+		state.includesResolved = true
+	}
+
+	// Empty files have no decoder and are parsed as empty maps:
+	var base map[string]LazyDecoder
+	if value != nil {
+		err = value.Decode(&base)
+		if err != nil {
+			return fmt.Errorf("expected the config file to be a map: %w", err)
+		}
+	}
+
+	var profiles map[string]LazyDecoder
+	if base[profilesKey] != nil {
+		err = base[profilesKey].Decode(&profiles)
+		if err != nil {
+			return fmt.Errorf("expected the '%s' key to be a map of profiles: %w", profilesKey, err)
+		}
+	}
+	delete(base, profilesKey)
+
+	merged := mapDecoder(base)
+	if profile != "" {
+		override, found := profiles[profile]
+		if !found {
+			return fmt.Errorf("unknown profile '%s', available profiles are: %s", profile, profileNames(profiles))
+		}
+
+		// Empty profiles are decoded as nil and have nothing to override:
+		if override != nil {
+			var overrideMap map[string]LazyDecoder
+			err = override.Decode(&overrideMap)
+			if err != nil {
+				return fmt.Errorf("expected profile '%s' to be a map: %w", profile, err)
+			}
+
+			merged, err = newLayerMerger(state, target).mergeLayer(merged, override, path)
+			if err != nil {
+				return fmt.Errorf("error merging profile '%s': %w", profile, err)
+			}
+		}
+	}
+
+	return state.parseRoot(target, merged)
+}
+
+func profileNames(profiles map[string]LazyDecoder) string {
+	if len(profiles) == 0 {
+		return "(none)"
+	}
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
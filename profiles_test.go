@@ -0,0 +1,159 @@
+package kparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestParseWithProfile(t *testing.T) {
+	type Config struct {
+		MaxRetries int      `yaml:"maxRetries" json:"maxRetries" default:"3"`
+		Domains    []string `yaml:"domains" json:"domains"`
+		Database   struct {
+			Host string `yaml:"host" json:"host" validate:"required"`
+			Port int    `yaml:"port" json:"port" default:"5432"`
+		} `yaml:"database" json:"database"`
+	}
+
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "config.yaml")
+	err := os.WriteFile(yamlPath, []byte(`domains: [localhost]
+database:
+  host: localhost
+
+profiles:
+  dev:
+  prod:
+    maxRetries: 5
+    domains: [example.com, www.example.com]
+    database:
+      host: db.example.com
+  broken:
+    database:
+      host: null
+`), 0o644)
+	tt.AssertNoErr(t, err)
+
+	jsonPath := filepath.Join(dir, "config.json")
+	err = os.WriteFile(jsonPath, []byte(`{
+		"database": {"host": "localhost"},
+		"profiles": {"prod": {"database": {"port": 5433}}}
+	}`), 0o644)
+	tt.AssertNoErr(t, err)
+
+	tests := []struct {
+		desc               string
+		path               string
+		profile            string
+		envProfile         string
+		expectedRetries    int
+		expectedDomains    []string
+		expectedHost       string
+		expectedPort       int
+		expectErrToContain []string
+	}{
+		{
+			desc:            "should use only the base values if no profile is chosen",
+			path:            yamlPath,
+			expectedRetries: 3,
+			expectedDomains: []string{"localhost"},
+			expectedHost:    "localhost",
+			expectedPort:    5432,
+		},
+		{
+			desc:            "should merge the profile over the base values",
+			path:            yamlPath,
+			profile:         "prod",
+			expectedRetries: 5,
+			expectedDomains: []string{"example.com", "www.example.com"},
+			expectedHost:    "db.example.com",
+			expectedPort:    5432,
+		},
+		{
+			desc:            "should accept empty profiles",
+			path:            yamlPath,
+			profile:         "dev",
+			expectedRetries: 3,
+			expectedDomains: []string{"localhost"},
+			expectedHost:    "localhost",
+			expectedPort:    5432,
+		},
+		{
+			desc:            "should work with json files",
+			path:            jsonPath,
+			profile:         "prod",
+			expectedRetries: 3,
+			expectedHost:    "localhost",
+			expectedPort:    5433,
+		},
+		{
+			desc:            "should select the profile from the env var",
+			path:            yamlPath,
+			profile:         "dev",
+			envProfile:      "prod",
+			expectedRetries: 5,
+			expectedDomains: []string{"example.com", "www.example.com"},
+			expectedHost:    "db.example.com",
+			expectedPort:    5432,
+		},
+		{
+			desc:               "should report unknown profiles",
+			path:               yamlPath,
+			profile:            "staging",
+			expectErrToContain: []string{"unknown profile 'staging'", "broken, dev, prod"},
+		},
+		{
+			desc:               "should validate the merged values",
+			path:               yamlPath,
+			profile:            "broken",
+			expectErrToContain: []string{"missing required field 'host'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Setenv("KPARSE_TEST_PROFILE", test.envProfile)
+
+			var config Config
+			err := ParseWithProfile(test.path, test.profile, &config, WithProfileEnvVar("KPARSE_TEST_PROFILE"), WithStrict(true))
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, config.MaxRetries, test.expectedRetries)
+			tt.AssertEqual(t, config.Domains, test.expectedDomains)
+			tt.AssertEqual(t, config.Database.Host, test.expectedHost)
+			tt.AssertEqual(t, config.Database.Port, test.expectedPort)
+		})
+	}
+
+	t.Run("should parse empty files as empty maps", func(t *testing.T) {
+		emptyPath := filepath.Join(t.TempDir(), "empty.yaml")
+		err := os.WriteFile(emptyPath, nil, 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config struct {
+			MaxRetries int `yaml:"maxRetries" default:"3"`
+		}
+		err = ParseWithProfile(emptyPath, "", &config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config.MaxRetries, 3)
+
+		err = ParseWithProfile(emptyPath, "prod", &config)
+		tt.AssertErrContains(t, err, "unknown profile 'prod'", "(none)")
+	})
+
+	t.Run("should record the origins of the profile values", func(t *testing.T) {
+		var config Config
+		var origins Origins
+		err := ParseWithProfile(yamlPath, "prod", &config, WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, origins["maxRetries"], Origin{Source: yamlPath, Line: 8, Column: 17})
+		tt.AssertEqual(t, origins["database.host"], Origin{Source: yamlPath, Line: 11, Column: 13})
+		tt.AssertEqual(t, origins["database.port"], Origin{Default: true})
+	})
+}
@@ -0,0 +1,201 @@
+package kparse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func MustParsePropertiesFile(path string, targetStruct any, opts ...Option) {
+	err := ParsePropertiesFile(path, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ParsePropertiesFile parses a Java .properties file into the target struct, see ParseProperties.
+func ParsePropertiesFile(path string, targetStruct any, opts ...Option) error {
+	return defaultParser.ParsePropertiesFile(path, targetStruct, opts...)
+}
+
+func MustParseProperties(file []byte, targetStruct any, opts ...Option) {
+	err := ParseProperties(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ParseProperties parses the contents of a Java .properties file into the
+// target struct, where the keys are read from the `properties` tag and
+// dotted keys, e.g. `database.host`, are parsed into nested structs.
+//
+// Values are converted to the types of the fields and lists are written
+// as comma separated values. Comments, line continuations and escapes
+// follow the format of java.util.Properties.
+func ParseProperties(file []byte, targetStruct any, opts ...Option) error {
+	return defaultParser.ParseProperties(file, targetStruct, opts...)
+}
+
+func MustParsePropertiesFromReader(file io.Reader, targetStruct any, opts ...Option) {
+	err := ParsePropertiesFromReader(file, targetStruct, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func ParsePropertiesFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	return defaultParser.ParsePropertiesFromReader(file, targetStruct, opts...)
+}
+
+// ParsePropertiesFile works like the top-level ParsePropertiesFile using the settings of the Parser.
+func (p *Parser) ParsePropertiesFile(path string, targetStruct any, opts ...Option) (err error) {
+	// The path is used as the source name unless the caller set another one:
+	opts = append([]Option{WithSourceName(path)}, opts...)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, file.Close())
+	}()
+
+	return p.ParsePropertiesFromReader(file, targetStruct, opts...)
+}
+
+// ParseProperties works like the top-level ParseProperties using the settings of the Parser.
+func (p *Parser) ParseProperties(file []byte, targetStruct any, opts ...Option) error {
+	return p.ParsePropertiesFromReader(bytes.NewReader(file), targetStruct, opts...)
+}
+
+// ParsePropertiesFromReader works like the top-level ParsePropertiesFromReader using the settings of the Parser.
+func (p *Parser) ParsePropertiesFromReader(file io.Reader, targetStruct any, opts ...Option) error {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	tree, err := parseProperties(string(content))
+	if err != nil {
+		return err
+	}
+
+	return p.newParseState("properties", opts).parseRoot(targetStruct, tree.decoder())
+}
+
+func parseProperties(content string) (textTree, error) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	tree := textTree{}
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+
+		line := strings.TrimLeft(lines[i], " \t\f")
+		indentation := len(lines[i]) - len(line)
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		// Lines ending with an odd number of backslashes continue on the next line:
+		for endsWithContinuation(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		line = strings.TrimSuffix(line, "\\")
+
+		keyEnd := 0
+		for keyEnd < len(line) && !strings.ContainsRune("=: \t\f", rune(line[keyEnd])) {
+			if line[keyEnd] == '\\' {
+				keyEnd++
+			}
+			keyEnd++
+		}
+		keyEnd = min(keyEnd, len(line))
+
+		start := keyEnd
+		for start < len(line) && strings.ContainsRune(" \t\f", rune(line[start])) {
+			start++
+		}
+		if start < len(line) && (line[start] == '=' || line[start] == ':') {
+			start++
+		}
+		for start < len(line) && strings.ContainsRune(" \t\f", rune(line[start])) {
+			start++
+		}
+
+		key, err := unescapeProperty(line[:keyEnd])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		value, err := unescapeProperty(line[start:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		path := strings.Split(key, ".")
+		if slices.Contains(path, "") {
+			return nil, fmt.Errorf("line %d: invalid key '%s'", lineNumber, key)
+		}
+
+		err = tree.set(path, textValueDecoder(value, lineNumber, indentation+start+1))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+
+	return tree, nil
+}
+
+func endsWithContinuation(line string) bool {
+	backslashes := len(line) - len(strings.TrimRight(line, "\\"))
+	return backslashes%2 == 1
+}
+
+// unescapeProperty replaces the escapes of .properties files, e.g.
+// `\t`, `\n` or `\u00e9`, any other escaped character is kept as is.
+func unescapeProperty(text string) (string, error) {
+	if !strings.Contains(text, "\\") {
+		return text, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			b.WriteByte(text[i])
+			continue
+		}
+
+		i++
+		switch text[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(text) {
+				return "", fmt.Errorf("invalid unicode escape '\\%s'", text[i:])
+			}
+
+			code, err := strconv.ParseUint(text[i+1:i+5], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape '\\%s'", text[i:i+5])
+			}
+			b.WriteRune(rune(code))
+			i += 4
+		default:
+			b.WriteByte(text[i])
+		}
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,117 @@
+package kparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestParseProperties(t *testing.T) {
+	type Config struct {
+		App struct {
+			Name string `properties:"name" validate:"required"`
+		} `properties:"app"`
+		Message  string   `properties:"message"`
+		Domains  []string `properties:"domains"`
+		Database struct {
+			Host string `properties:"host" default:"localhost"`
+			Port int    `properties:"port" validate:">0"`
+			URL  string `properties:"url"`
+		} `properties:"database"`
+	}
+
+	tests := []struct {
+		desc               string
+		file               string
+		opts               []Option
+		expectedConfig     func(config *Config)
+		expectErrToContain []string
+	}{
+		{
+			desc: "should parse dotted keys into nested structs",
+			file: `# comment
+! also a comment
+app.name = My App
+message : caf\u00e9\tis open \
+          every day
+domains=a.com,\
+  b.com
+database.port 5433
+database.url = jdbc:postgresql://localhost/app\=1
+`,
+			expectedConfig: func(config *Config) {
+				config.App.Name = "My App"
+				config.Message = "café\tis open every day"
+				config.Domains = []string{"a.com", "b.com"}
+				config.Database.Host = "localhost"
+				config.Database.Port = 5433
+				config.Database.URL = "jdbc:postgresql://localhost/app=1"
+			},
+		},
+		{
+			desc: "should accept escaped separators on the keys",
+			file: "app.name=My App\nmessage\\ key = value\n",
+			expectedConfig: func(config *Config) {
+				config.App.Name = "My App"
+				config.Database.Host = "localhost"
+			},
+		},
+		{
+			desc:               "should validate the values",
+			file:               "app.name=My App\ndatabase.port=0\n",
+			expectErrToContain: []string{"Port", "> 0"},
+		},
+		{
+			desc:               "should report unknown keys in strict mode",
+			file:               "app.name=My App\napp.version=2\n",
+			opts:               []Option{WithStrict(true)},
+			expectErrToContain: []string{"unknown keys: 'app.version'"},
+		},
+		{
+			desc:               "should report keys used as values and nested keys",
+			file:               "database=postgres\ndatabase.port=5433\n",
+			expectErrToContain: []string{"line 2: key 'database' is used both for a value and for nested keys"},
+		},
+		{
+			desc:               "should report invalid keys",
+			file:               "app..name=My App\n",
+			expectErrToContain: []string{"line 1: invalid key 'app..name'"},
+		},
+		{
+			desc:               "should report invalid unicode escapes",
+			file:               "app.name=\\u00zz\n",
+			expectErrToContain: []string{"line 1: invalid unicode escape '\\u00zz'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var config Config
+			err := ParseProperties([]byte(test.file), &config, test.opts...)
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+
+			var expected Config
+			test.expectedConfig(&expected)
+			tt.AssertEqual(t, config, expected)
+		})
+	}
+
+	t.Run("should record the origins of the values", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.properties")
+		err := os.WriteFile(path, []byte("app.name=My App\n  database.port = 5433\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config Config
+		var origins Origins
+		err = ParsePropertiesFile(path, &config, WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, origins["app.name"], Origin{Source: path, Line: 1, Column: 10})
+		tt.AssertEqual(t, origins["database.port"], Origin{Source: path, Line: 2, Column: 19})
+	})
+}
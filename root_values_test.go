@@ -0,0 +1,185 @@
+package kparse
+
+import (
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestParseRootValues(t *testing.T) {
+	type Route struct {
+		Path    string `yaml:"path" json:"path" validate:"required"`
+		Timeout int    `yaml:"timeout" json:"timeout" default:"30" validate:"<=60"`
+	}
+
+	type Backend struct {
+		URL    string  `yaml:"url" json:"url" validate:"required"`
+		Routes []Route `yaml:"routes" json:"routes"`
+	}
+
+	type Config struct {
+		Backends map[string]Backend `yaml:"backends" json:"backends"`
+	}
+
+	tests := []struct {
+		desc               string
+		format             string
+		input              string
+		target             any
+		expected           any
+		expectErrToContain []string
+	}{
+		{
+			desc:   "should parse slices of structs at the root",
+			format: "yaml",
+			input: `- path: /foo
+- path: /bar
+  timeout: 10
+`,
+			target: &[]Route{},
+			expected: &[]Route{
+				{Path: "/foo", Timeout: 30},
+				{Path: "/bar", Timeout: 10},
+			},
+		},
+		{
+			desc:   "should parse maps of structs at the root",
+			format: "json",
+			input:  `{"backendA": {"url": "http://a", "routes": [{"path": "/a"}]}, "backendB": {"url": "http://b"}}`,
+			target: &map[string]Backend{},
+			expected: &map[string]Backend{
+				"backendA": {URL: "http://a", Routes: []Route{{Path: "/a", Timeout: 30}}},
+				"backendB": {URL: "http://b"},
+			},
+		},
+		{
+			desc:   "should parse arrays of structs at the root",
+			format: "json",
+			input:  `[{"path": "/foo"}, {"path": "/bar"}]`,
+			target: &[2]Route{},
+			expected: &[2]Route{
+				{Path: "/foo", Timeout: 30},
+				{Path: "/bar", Timeout: 30},
+			},
+		},
+		{
+			desc:     "should parse slices of scalars at the root",
+			format:   "yaml",
+			input:    `[foo, bar]`,
+			target:   &[]string{},
+			expected: &[]string{"foo", "bar"},
+		},
+		{
+			desc:   "should parse nested collections of structs",
+			format: "yaml",
+			input: `groupA:
+  - path: /foo
+`,
+			target: &map[string][]Route{},
+			expected: &map[string][]Route{
+				"groupA": {{Path: "/foo", Timeout: 30}},
+			},
+		},
+		{
+			desc:   "should report the errors of every element",
+			format: "yaml",
+			input: `- path: /foo
+  timeout: 90
+- timeout: 10
+`,
+			target:             &[]Route{},
+			expectErrToContain: []string{"element 0", "Timeout", "element 1", "missing required field 'path'"},
+		},
+		{
+			desc:               "should report errors with the map keys",
+			format:             "json",
+			input:              `{"backendA": {"routes": []}}`,
+			target:             &map[string]Backend{},
+			expectErrToContain: []string{"key 'backendA'", "missing required field 'url'"},
+		},
+		{
+			desc:   "should parse maps of structs on struct fields",
+			format: "yaml",
+			input: `backends:
+  backendA:
+    url: http://a
+    routes: [{path: /a}]
+`,
+			target: &Config{},
+			expected: &Config{
+				Backends: map[string]Backend{
+					"backendA": {URL: "http://a", Routes: []Route{{Path: "/a", Timeout: 30}}},
+				},
+			},
+		},
+		{
+			desc:               "should validate maps of structs on struct fields",
+			format:             "json",
+			input:              `{"backends": {"backendA": {"routes": [{"path": "/a", "timeout": 90}]}}}`,
+			target:             &Config{},
+			expectErrToContain: []string{"key 'backendA'", "missing required field 'url'"},
+		},
+		{
+			desc:   "should run the validations of maps of structs on struct fields",
+			format: "yaml",
+			input:  "backends: {}\n",
+			target: &struct {
+				Backends map[string]Backend `yaml:"backends" validate:"len>=1"`
+			}{},
+			expectErrToContain: []string{"Backends", "len 0 should be >= 1"},
+		},
+		{
+			desc:               "should report arrays with the wrong number of elements",
+			format:             "json",
+			input:              `[{"path": "/foo"}]`,
+			target:             &[2]Route{},
+			expectErrToContain: []string{"expected 2 elements but got 1"},
+		},
+		{
+			desc:               "should report maps with keys that are not strings",
+			format:             "yaml",
+			input:              `1: {path: /foo}`,
+			target:             &map[int]Route{},
+			expectErrToContain: []string{"unsupported map key type int"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var err error
+			if test.format == "json" {
+				err = ParseJSON([]byte(test.input), test.target)
+			} else {
+				err = ParseYAML([]byte(test.input), test.target)
+			}
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, test.target, test.expected)
+		})
+	}
+
+	t.Run("should record the origins of the elements", func(t *testing.T) {
+		var routes []Route
+		var origins Origins
+		err := ParseYAML([]byte("- path: /foo\n"), &routes, WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, origins, Origins{
+			"[0].path":    {Line: 1, Column: 9},
+			"[0].timeout": {Default: true},
+		})
+	})
+
+	t.Run("should record the origins of maps of structs on struct fields", func(t *testing.T) {
+		var config Config
+		var origins Origins
+		err := ParseYAML([]byte("backends:\n  backendA: {url: http://a}\n"), &config, WithOrigins(&origins))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, origins, Origins{
+			"backends":              {Line: 2, Column: 3},
+			"backends.backendA.url": {Line: 2, Column: 19},
+		})
+	})
+}
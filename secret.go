@@ -0,0 +1,157 @@
+package kparse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted is what we output in place of secret values.
+const redacted = "******"
+
+// Secret wraps config values that should never be printed.
+//
+// It decodes as the wrapped type but is always formatted as "******" by
+// the fmt and log/slog packages and when encoded as JSON or YAML, the
+// actual value is only accessible through the Value() method.
+//
+// Fields of this type are also treated as secret by kparse, so their
+// values are redacted from validation errors and from Dump.
+type Secret[T any] struct {
+	value T
+}
+
+// NewSecret wraps a value on a Secret.
+func NewSecret[T any](value T) Secret[T] {
+	return Secret[T]{value: value}
+}
+
+// Value returns the wrapped secret value.
+func (s Secret[T]) Value() T {
+	return s.value
+}
+
+// String implements the fmt.Stringer interface.
+func (s Secret[T]) String() string {
+	return redacted
+}
+
+// GoString implements the fmt.GoStringer interface.
+func (s Secret[T]) GoString() string {
+	return redacted
+}
+
+// Format implements the fmt.Formatter interface, so the
+// secret is redacted regardless of the verb being used.
+func (s Secret[T]) Format(f fmt.State, verb rune) {
+	_, _ = io.WriteString(f, redacted)
+}
+
+// LogValue implements the slog.LogValuer interface.
+func (s Secret[T]) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (s Secret[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redacted)
+}
+
+// MarshalYAML implements the yaml.Marshaler interface.
+func (s Secret[T]) MarshalYAML() (any, error) {
+	return redacted, nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (s *Secret[T]) UnmarshalJSON(b []byte) error {
+	err := json.Unmarshal(b, &s.value)
+	if err != nil {
+		return fmt.Errorf("can't decode secret value into type %T", s.value)
+	}
+	return nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (s *Secret[T]) UnmarshalYAML(node *yaml.Node) error {
+	// The errors from the YAML decoder contain the value,
+	// so we can't return them as they are:
+	err := node.Decode(&s.value)
+	if err != nil {
+		return fmt.Errorf("can't decode secret value into type %T", s.value)
+	}
+	return nil
+}
+
+// secretValuePtr allows the parser to validate the wrapped value.
+func (s *Secret[T]) secretValuePtr() any {
+	return &s.value
+}
+
+// secretValue is implemented by all *Secret[T] types.
+type secretValue interface {
+	secretValuePtr() any
+}
+
+var secretValueType = reflect.TypeOf((*secretValue)(nil)).Elem()
+
+func isSecretType(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(secretValueType)
+}
+
+// isSecretField reports if a field was marked as secret either by
+// using the Secret type, the `secret:"true"` tag or the `secretFile` tag.
+func isSecretField(t reflect.Type, tags map[string]string) bool {
+	return tags["secret"] == "true" || tags["secretFile"] != "" || isSecretType(t)
+}
+
+// unwrapSecretType returns the type wrapped by a Secret type, or the
+// input type itself for any other type, it is used for deciding which
+// validators and schema keywords apply to a field.
+func unwrapSecretType(t reflect.Type) reflect.Type {
+	if !isSecretType(t) {
+		return t
+	}
+
+	secret := reflect.New(t).Interface().(secretValue)
+	return reflect.TypeOf(secret.secretValuePtr()).Elem()
+}
+
+// redactValidator wraps the validators of secret fields so the
+// error messages don't include the value being validated.
+func redactValidator(fieldName string, exp string, validator Validator) Validator {
+	return func(value any) error {
+		if validator(value) == nil {
+			return nil
+		}
+
+		return fmt.Errorf(
+			"field %q with value %s should satisfy the validation: '%s'",
+			fieldName, redacted, exp,
+		)
+	}
+}
+
+// Dump renders the struct pointed by structPtr as YAML using
+// the `tagName` tag for the keys, replacing the values of all
+// secret fields with "******", so it is safe for logging.
+//
+// Fields are considered secret if they have the `secret:"true"`
+// or `secretFile` tags or if they are of the Secret type.
+func Dump(structPtr any, tagName string) (string, error) {
+	v := reflect.ValueOf(structPtr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return "", fmt.Errorf("expected non-nil struct pointer but got: %T", structPtr)
+	}
+
+	node, err := valueNode(tagName, v.Elem(), true)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := encodeNodeAsYAML(node)
+	return string(output), err
+}
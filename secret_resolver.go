@@ -0,0 +1,178 @@
+package kparse
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SecretResolver loads the secrets referenced on config files.
+//
+// Values of secret fields written as `${<provider>:<ref>}` are passed to
+// the resolver registered for <provider>, e.g. `${file:/run/secrets/db}`
+// reads the contents of the /run/secrets/db file.
+type SecretResolver interface {
+	ResolveSecret(ref string) (string, error)
+}
+
+// SecretResolverFunc allows ordinary functions to be used as SecretResolvers.
+type SecretResolverFunc func(ref string) (string, error)
+
+// ResolveSecret implements the SecretResolver interface.
+func (fn SecretResolverFunc) ResolveSecret(ref string) (string, error) {
+	return fn(ref)
+}
+
+var secretResolvers sync.Map
+
+func init() {
+	RegisterSecretResolver("file", SecretResolverFunc(readSecretFile))
+	RegisterSecretResolver("env", SecretResolverFunc(readSecretEnv))
+}
+
+// RegisterSecretResolver makes the resolver available for references
+// in the form `${<provider>:<ref>}`, registering a resolver for an
+// existing provider replaces it.
+//
+// The "file" and "env" providers are registered by default.
+func RegisterSecretResolver(provider string, resolver SecretResolver) {
+	secretResolvers.Store(provider, resolver)
+}
+
+var secretReferenceRegex = regexp.MustCompile(`^\$\{([A-Za-z][A-Za-z0-9_-]*):(.*)\}$`)
+
+// resolveSecrets checks if the value is an encrypted value, or a secret
+// reference if references is true, and if so returns a new LazyDecoder
+// with the secret value. The items of lists and maps decoded into t
+// are checked the same way.
+func resolveSecrets(value LazyDecoder, t reflect.Type, references bool) (_ LazyDecoder, resolved bool, _ error) {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return resolveSecrets(value, t.Elem(), references)
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			break
+		}
+
+		var items []LazyDecoder
+		if value.Decode(&items) != nil {
+			// Decoding errors are reported when decoding the field:
+			return value, false, nil
+		}
+
+		for i, item := range items {
+			if item == nil {
+				continue
+			}
+
+			item, itemResolved, err := resolveSecrets(item, t.Elem(), references)
+			if err != nil {
+				return nil, false, fmt.Errorf("error on element %d: %w", i, err)
+			}
+			items[i] = item
+			resolved = resolved || itemResolved
+		}
+		if !resolved {
+			return value, false, nil
+		}
+		return sliceDecoder(items), true, nil
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			break
+		}
+
+		var items map[string]LazyDecoder
+		if value.Decode(&items) != nil {
+			return value, false, nil
+		}
+
+		for key, item := range items {
+			if item == nil {
+				continue
+			}
+
+			item, itemResolved, err := resolveSecrets(item, t.Elem(), references)
+			if err != nil {
+				return nil, false, fmt.Errorf("error on key '%s': %w", key, err)
+			}
+			items[key] = item
+			resolved = resolved || itemResolved
+		}
+		if !resolved {
+			return value, false, nil
+		}
+		return mapDecoder(items), true, nil
+	}
+
+	return resolveSecretReference(value, references)
+}
+
+// resolveSecretReference checks if the value is an encrypted value or, if
+// references is true, a secret reference of a registered provider and if
+// so returns a new LazyDecoder with the secret value.
+func resolveSecretReference(value LazyDecoder, references bool) (_ LazyDecoder, resolved bool, _ error) {
+	var raw string
+	err := value.Decode(&raw)
+	if err != nil {
+		// Only strings can be secret references:
+		return value, false, nil
+	}
+
+	if _, encrypted := encryptedPayload(raw); encrypted {
+		plaintext, err := decryptValue(raw)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return scalarDecoder(plaintext), true, nil
+	}
+
+	if !references {
+		return value, false, nil
+	}
+
+	match := secretReferenceRegex.FindStringSubmatch(raw)
+	if match == nil {
+		return value, false, nil
+	}
+
+	// Values that only look like references, e.g. `${PORT:8080}`, are kept as they are:
+	provider, ref := match[1], match[2]
+	resolver, found := secretResolvers.Load(provider)
+	if !found {
+		return value, false, nil
+	}
+
+	secret, err := resolver.(SecretResolver).ResolveSecret(ref)
+	if err != nil {
+		return nil, false, fmt.Errorf("error loading secret '%s' from provider '%s': %w", ref, provider, err)
+	}
+
+	return scalarDecoder(secret), true, nil
+}
+
+// readSecretFile reads the contents of a secret file ignoring the
+// trailing line break that most editors and tools add to files.
+func readSecretFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	secret := strings.TrimSuffix(string(content), "\n")
+	return strings.TrimSuffix(secret, "\r"), nil
+}
+
+func readSecretEnv(name string) (string, error) {
+	secret, found := os.LookupEnv(name)
+	if !found {
+		return "", fmt.Errorf("environment variable '%s' is not set", name)
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,160 @@
+package kparse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestSecretResolvers(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	err := os.WriteFile(passwordFile, []byte("fakePassword\n"), 0o600)
+	tt.AssertNoErr(t, err)
+	portFile := filepath.Join(dir, "port")
+	err = os.WriteFile(portFile, []byte("5432"), 0o600)
+	tt.AssertNoErr(t, err)
+
+	t.Setenv("KPARSE_TEST_TOKEN", "fakeToken")
+
+	RegisterSecretResolver("fakevault", SecretResolverFunc(func(ref string) (string, error) {
+		if ref != "db/password" {
+			return "", fmt.Errorf("secret not found")
+		}
+		return "fakeVaultPassword", nil
+	}))
+
+	t.Run("should resolve references from the built-in and custom providers", func(t *testing.T) {
+		var config struct {
+			Password      string         `yaml:"password" secret:"true"`
+			Port          int            `yaml:"port" secret:"true"`
+			Token         Secret[string] `yaml:"token"`
+			VaultPassword string         `yaml:"vaultPassword" secret:"true"`
+			NotReference  string         `yaml:"notReference" secret:"true"`
+		}
+		err := ParseYAML([]byte(`
+password: ${file:`+passwordFile+`}
+port: ${file:`+portFile+`}
+token: ${env:KPARSE_TEST_TOKEN}
+vaultPassword: ${fakevault:db/password}
+notReference: prefix ${env:KPARSE_TEST_TOKEN}
+`), &config)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, config.Password, "fakePassword")
+		tt.AssertEqual(t, config.Port, 5432)
+		tt.AssertEqual(t, config.Token.Value(), "fakeToken")
+		tt.AssertEqual(t, config.VaultPassword, "fakeVaultPassword")
+		tt.AssertEqual(t, config.NotReference, "prefix ${env:KPARSE_TEST_TOKEN}")
+	})
+
+	t.Run("should resolve references on the items of secret lists and maps", func(t *testing.T) {
+		var config struct {
+			Passwords []string          `yaml:"passwords" secret:"true"`
+			Tokens    map[string]string `yaml:"tokens" secret:"true"`
+			Backends  []struct {
+				Password string `yaml:"password" secret:"true"`
+			} `yaml:"backends"`
+		}
+		err := ParseYAML([]byte(`
+passwords: ["${file:`+passwordFile+`}", plainPassword]
+tokens:
+  api: ${env:KPARSE_TEST_TOKEN}
+backends:
+  - password: ${fakevault:db/password}
+`), &config)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, config.Passwords, []string{"fakePassword", "plainPassword"})
+		tt.AssertEqual(t, config.Tokens, map[string]string{"api": "fakeToken"})
+		tt.AssertEqual(t, config.Backends[0].Password, "fakeVaultPassword")
+	})
+
+	t.Run("should not resolve references on fields not marked as secret", func(t *testing.T) {
+		var config struct {
+			Name     string   `yaml:"name"`
+			Hosts    []string `yaml:"hosts"`
+			Password string   `yaml:"password" secret:"true"`
+		}
+		err := ParseYAML([]byte(`
+name: ${file:`+passwordFile+`}
+hosts: ["${env:KPARSE_TEST_TOKEN}"]
+password: ${PORT:8080}
+`), &config)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, config.Name, "${file:"+passwordFile+"}")
+		tt.AssertEqual(t, config.Hosts, []string{"${env:KPARSE_TEST_TOKEN}"})
+
+		// Providers that are not registered are kept as text:
+		tt.AssertEqual(t, config.Password, "${PORT:8080}")
+	})
+
+	t.Run("should load secrets from the secretFile tag", func(t *testing.T) {
+		var config struct {
+			Password    string `yaml:"password" secretFile:"testdata/secret_password"`
+			Overwritten string `yaml:"overwritten" secretFile:"testdata/secret_password"`
+			MissingFile string `yaml:"missingFile" secretFile:"testdata/missing" default:"defaultPassword"`
+		}
+		err := ParseYAML([]byte("overwritten: fromConfig"), &config)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, config.Password, "fakeFilePassword")
+		tt.AssertEqual(t, config.Overwritten, "fromConfig")
+		tt.AssertEqual(t, config.MissingFile, "defaultPassword")
+	})
+
+	t.Run("should report errors", func(t *testing.T) {
+		tests := []struct {
+			desc               string
+			input              string
+			targetStruct       any
+			expectErrToContain []string
+		}{
+			{
+				desc:  "missing file",
+				input: "password: ${file:" + filepath.Join(dir, "missing") + "}",
+				targetStruct: &struct {
+					Password string `yaml:"password" secret:"true"`
+				}{},
+				expectErrToContain: []string{"password", "missing", "file"},
+			},
+			{
+				desc:  "missing env var",
+				input: "password: ${env:KPARSE_TEST_MISSING_VAR}",
+				targetStruct: &struct {
+					Password string `yaml:"password" secret:"true"`
+				}{},
+				expectErrToContain: []string{"password", "KPARSE_TEST_MISSING_VAR", "not set"},
+			},
+			{
+				desc:  "failed validations without the resolved value",
+				input: "password: ${file:" + passwordFile + "}",
+				targetStruct: &struct {
+					Password string `yaml:"password" validate:"len>=20" secret:"true"`
+				}{},
+				expectErrToContain: []string{"Password", "******", "len>=20"},
+			},
+			{
+				desc:  "decoding errors without the resolved value",
+				input: "port: ${file:" + passwordFile + "}",
+				targetStruct: &struct {
+					Port int `yaml:"port" secret:"true"`
+				}{},
+				expectErrToContain: []string{"secret", "port", "int"},
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.desc, func(t *testing.T) {
+				err := ParseYAML([]byte(test.input), test.targetStruct)
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				tt.AssertEqual(t, strings.Contains(err.Error(), "fakePassword"), false, err.Error())
+			})
+		}
+	})
+}
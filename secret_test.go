@@ -0,0 +1,204 @@
+package kparse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestSecret(t *testing.T) {
+	t.Run("should redact the value when formatting", func(t *testing.T) {
+		config := struct {
+			User     string
+			Password Secret[string]
+		}{
+			User:     "fakeUser",
+			Password: NewSecret("fakePassword"),
+		}
+
+		for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q"} {
+			output := fmt.Sprintf(format, config)
+			tt.AssertEqual(t, strings.Contains(output, "fakePassword"), false, "format: %s, output: %s", format, output)
+			tt.AssertEqual(t, strings.Contains(output, "******"), true, "format: %s, output: %s", format, output)
+		}
+
+		var logs bytes.Buffer
+		slog.New(slog.NewTextHandler(&logs, nil)).Info("config loaded", "password", config.Password)
+		tt.AssertEqual(t, strings.Contains(logs.String(), "password=******"), true, logs.String())
+
+		rawJSON, err := json.Marshal(config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, string(rawJSON), `{"User":"fakeUser","Password":"******"}`)
+
+		tt.AssertEqual(t, config.Password.Value(), "fakePassword")
+	})
+
+	t.Run("should parse secrets from yaml and json", func(t *testing.T) {
+		var yamlConfig struct {
+			Password Secret[string] `yaml:"password" validate:"required"`
+			Port     Secret[int]    `yaml:"port" default:"8080"`
+		}
+		err := ParseYAML([]byte("password: fakePassword"), &yamlConfig)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, yamlConfig.Password.Value(), "fakePassword")
+		tt.AssertEqual(t, yamlConfig.Port.Value(), 8080)
+
+		var jsonConfig struct {
+			Password Secret[string] `json:"password"`
+		}
+		err = ParseJSON([]byte(`{"password": "fakePassword"}`), &jsonConfig)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, jsonConfig.Password.Value(), "fakePassword")
+	})
+
+	t.Run("should not include secret values on errors", func(t *testing.T) {
+		tests := []struct {
+			desc               string
+			input              string
+			targetStruct       any
+			expectErrToContain []string
+		}{
+			{
+				desc:  "len validation on secret type",
+				input: "password: hunter2",
+				targetStruct: &struct {
+					Password Secret[string] `yaml:"password" validate:"len>=8"`
+				}{},
+				expectErrToContain: []string{"Password", "******", "len>=8"},
+			},
+			{
+				desc:  "range validation on secret tag",
+				input: "pin: 4242",
+				targetStruct: &struct {
+					Pin int `yaml:"pin" secret:"true" validate:"<1000"`
+				}{},
+				expectErrToContain: []string{"Pin", "******", "<1000"},
+			},
+			{
+				desc:  "decoding error on secret type",
+				input: "pin: hunter2",
+				targetStruct: &struct {
+					Pin Secret[int] `yaml:"pin"`
+				}{},
+				expectErrToContain: []string{"secret", "int"},
+			},
+			{
+				desc:  "decoding error on secret tag",
+				input: "pin: hunter2",
+				targetStruct: &struct {
+					Pin int `yaml:"pin" secret:"true"`
+				}{},
+				expectErrToContain: []string{"secret", "pin", "int"},
+			},
+			{
+				desc:  "validation on field of secret struct",
+				input: "auth: {pin: 4242}",
+				targetStruct: &struct {
+					Auth struct {
+						Pin int `yaml:"pin" validate:"<1000"`
+					} `yaml:"auth" secret:"true"`
+				}{},
+				expectErrToContain: []string{"Pin", "******", "<1000"},
+			},
+			{
+				desc:  "decoding error on field of secret struct",
+				input: "auth: {pin: hunter2}",
+				targetStruct: &struct {
+					Auth struct {
+						Pin int `yaml:"pin"`
+					} `yaml:"auth" secret:"true"`
+				}{},
+				expectErrToContain: []string{"secret", "pin", "int"},
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.desc, func(t *testing.T) {
+				err := ParseYAML([]byte(test.input), test.targetStruct)
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				tt.AssertEqual(t, strings.Contains(err.Error(), "hunter2"), false, err.Error())
+				tt.AssertEqual(t, strings.Contains(err.Error(), "4242"), false, err.Error())
+			})
+		}
+	})
+}
+
+func TestDump(t *testing.T) {
+	config := struct {
+		User     string         `yaml:"user"`
+		Password Secret[string] `yaml:"password"`
+		Database struct {
+			Host  string `yaml:"host"`
+			Token string `yaml:"token" secret:"true"`
+		} `yaml:"database"`
+		Replicas []struct {
+			Host     string         `yaml:"host"`
+			Password Secret[string] `yaml:"password"`
+		} `yaml:"replicas"`
+		Ignored string
+		Cache   string `yaml:"-"`
+		Session string `yaml:"-"`
+	}{
+		User:     "fakeUser",
+		Password: NewSecret("fakePassword"),
+		Ignored:  "notDumped",
+		Cache:    "notDumped",
+		Session:  "notDumped",
+	}
+	config.Database.Host = "fakeHost"
+	config.Database.Token = "fakeToken"
+	config.Replicas = append(config.Replicas, struct {
+		Host     string         `yaml:"host"`
+		Password Secret[string] `yaml:"password"`
+	}{
+		Host:     "fakeReplica",
+		Password: NewSecret("fakeReplicaPassword"),
+	})
+
+	output, err := Dump(&config, "yaml")
+	tt.AssertNoErr(t, err)
+	tt.AssertEqual(t, output, `user: fakeUser
+password: '******'
+database:
+  host: fakeHost
+  token: '******'
+replicas:
+  - host: fakeReplica
+    password: '******'
+`)
+}
+
+func TestDumpTypesWithMarshalers(t *testing.T) {
+	var config struct {
+		StartedAt time.Time      `yaml:"startedAt"`
+		Address   net.IP         `yaml:"address"`
+		Token     Secret[string] `yaml:"token"`
+	}
+	var origins Origins
+	err := ParseYAML([]byte(`startedAt: 2024-05-01T10:30:00Z
+address: 10.0.0.1
+token: fakeToken
+`), &config, WithOrigins(&origins))
+	tt.AssertNoErr(t, err)
+
+	output, err := Dump(&config, "yaml")
+	tt.AssertNoErr(t, err)
+	tt.AssertEqual(t, output, `startedAt: 2024-05-01T10:30:00Z
+address: 10.0.0.1
+token: '******'
+`)
+
+	output, err = DumpWithOrigins(&config, "yaml", origins)
+	tt.AssertNoErr(t, err)
+	tt.AssertEqual(t, output, `startedAt: 2024-05-01T10:30:00Z # line 1, column 12
+address: 10.0.0.1 # line 2, column 10
+token: '******' # line 3, column 8
+`)
+}
@@ -0,0 +1,290 @@
+package kparse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source provides the raw values of a config for Parser.Load.
+type Source interface {
+	// Name describes the source on errors and origins, e.g. a file path.
+	Name() string
+
+	// Load reads the values of the source, usually a map.
+	Load() (LazyDecoder, error)
+}
+
+type source struct {
+	name string
+	load func() (LazyDecoder, error)
+}
+
+func (s source) Name() string {
+	return s.name
+}
+
+func (s source) Load() (LazyDecoder, error) {
+	return s.load()
+}
+
+// FileSource reads a YAML or JSON file, the format
+// is chosen by the extension: .yaml, .yml or .json.
+func FileSource(path string) Source {
+	return source{
+		name: path,
+		load: func() (LazyDecoder, error) {
+			format, err := formatFromPath(path)
+			if err != nil {
+				return nil, err
+			}
+
+			file, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+
+			if format == "json" {
+				return decodeJSONSource(file)
+			}
+			return decodeYAMLSource(file)
+		},
+	}
+}
+
+// formatFromPath returns the format of a config file from its extension.
+func formatFromPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml", nil
+	case ".json":
+		return "json", nil
+	}
+
+	return "", fmt.Errorf("unsupported file extension on '%s', expected one of: .yaml, .yml or .json", path)
+}
+
+// YAMLSource reads the values from a YAML file already loaded in memory.
+func YAMLSource(name string, file []byte) Source {
+	return source{
+		name: name,
+		load: func() (LazyDecoder, error) {
+			return decodeYAMLSource(file)
+		},
+	}
+}
+
+// JSONSource reads the values from a JSON file already loaded in memory.
+func JSONSource(name string, file []byte) Source {
+	return source{
+		name: name,
+		load: func() (LazyDecoder, error) {
+			return decodeJSONSource(file)
+		},
+	}
+}
+
+func decodeYAMLSource(file []byte) (LazyDecoder, error) {
+	var value LazyDecoder
+	err := yaml.NewDecoder(bytes.NewReader(file)).Decode(&value)
+	if err == io.EOF {
+		// Empty files have no values
+		return nil, nil
+	}
+	return value, err
+}
+
+func decodeJSONSource(file []byte) (LazyDecoder, error) {
+	var value LazyDecoder
+	err := json.Unmarshal(file, &value)
+	return value, err
+}
+
+// withSource tags the value and all values nested inside
+// it with the name of their source, for recording origins.
+func withSource(name string, value LazyDecoder) LazyDecoder {
+	if value == nil {
+		return nil
+	}
+
+	return func(target any) error {
+		switch target := target.(type) {
+		case *originProbe:
+			target.source = name
+
+		case *map[string]LazyDecoder:
+			err := value(target)
+			for key, item := range *target {
+				(*target)[key] = withSource(name, item)
+			}
+			return err
+
+		case *[]LazyDecoder:
+			err := value(target)
+			for i, item := range *target {
+				(*target)[i] = withSource(name, item)
+			}
+			return err
+		}
+
+		return value(target)
+	}
+}
+
+// mergeDecoders merges the maps of both values recursively,
+// for any other values the override value is used.
+func mergeDecoders(base LazyDecoder, override LazyDecoder) LazyDecoder {
+	// Null values are decoded as nil decoders, and override the base as missing values:
+	if base == nil || override == nil {
+		return override
+	}
+
+	var baseMap, overrideMap map[string]LazyDecoder
+	if base.Decode(&baseMap) != nil || override.Decode(&overrideMap) != nil || baseMap == nil || overrideMap == nil {
+		return override
+	}
+
+	merged := maps.Clone(baseMap)
+	for key, value := range overrideMap {
+		merged[key] = mergeDecoders(merged[key], value)
+	}
+
+	return mapDecoder(merged)
+}
+
+// mapDecoder returns a LazyDecoder for maps built in memory,
+// e.g. when merging the values of multiple sources.
+func mapDecoder(m map[string]LazyDecoder) LazyDecoder {
+	return func(target any) error {
+		switch target := target.(type) {
+		case *map[string]LazyDecoder:
+			*target = maps.Clone(m)
+			return nil
+		case *originProbe:
+			// Maps built in memory have no position
+			return nil
+		}
+
+		// Maps are decoded item by item, so each value is decoded by its
+		// original decoder, e.g. keeping the precision of JSON numbers:
+		v := reflect.ValueOf(target)
+		isMap := v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Map
+		if isMap && v.Elem().Type().Key().Kind() == reflect.String && !hasUnmarshaler(v.Elem().Type()) {
+			return decodeMapItems(m, v.Elem())
+		}
+
+		// For other types we decode each item and use
+		// the YAML decoder for converting the results:
+		values := make(map[string]any, len(m))
+		for key, value := range m {
+			if value == nil {
+				values[key] = nil
+				continue
+			}
+
+			var item any
+			err := value.Decode(&item)
+			if err != nil {
+				return err
+			}
+			values[key] = item
+		}
+
+		var node yaml.Node
+		err := node.Encode(values)
+		if err != nil {
+			return err
+		}
+
+		return node.Decode(target)
+	}
+}
+
+// sliceDecoder returns a LazyDecoder for slices built in memory,
+// e.g. when some of their items were replaced by included files.
+func sliceDecoder(items []LazyDecoder) LazyDecoder {
+	return func(target any) error {
+		switch target := target.(type) {
+		case *[]LazyDecoder:
+			*target = slices.Clone(items)
+			return nil
+		case *originProbe:
+			// Slices built in memory have no position
+			return nil
+		}
+
+		v := reflect.ValueOf(target)
+		if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Slice && !hasUnmarshaler(v.Elem().Type()) {
+			return decodeSliceItems(items, v.Elem())
+		}
+
+		values := make([]any, len(items))
+		for i, item := range items {
+			if item == nil {
+				continue
+			}
+
+			err := item.Decode(&values[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		var node yaml.Node
+		err := node.Encode(values)
+		if err != nil {
+			return err
+		}
+
+		return node.Decode(target)
+	}
+}
+
+// decodeMapItems decodes each value into a new element of the target map,
+// existing maps are kept and only receive the new keys, as on the YAML decoder.
+func decodeMapItems(m map[string]LazyDecoder, target reflect.Value) error {
+	t := target.Type()
+	if target.IsNil() {
+		target.Set(reflect.MakeMapWithSize(t, len(m)))
+	}
+
+	for key, value := range m {
+		item := reflect.New(t.Elem())
+		if value != nil {
+			err := value.Decode(item.Interface())
+			if err != nil {
+				return err
+			}
+		}
+		target.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), item.Elem())
+	}
+
+	return nil
+}
+
+// decodeSliceItems decodes each item into a new slice set on target.
+func decodeSliceItems(items []LazyDecoder, target reflect.Value) error {
+	slice := reflect.MakeSlice(target.Type(), len(items), len(items))
+	for i, item := range items {
+		if item == nil {
+			continue
+		}
+
+		err := item.Decode(slice.Index(i).Addr().Interface())
+		if err != nil {
+			return err
+		}
+	}
+
+	target.Set(slice)
+	return nil
+}
@@ -0,0 +1,96 @@
+package kparse
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// textTree holds the values of formats where every value is a
+// string, e.g. INI and .properties files, arranged into nested maps,
+// where each item is either a LazyDecoder or another textTree.
+type textTree map[string]any
+
+// section returns the nested map of the given path, creating it if needed.
+func (t textTree) section(path []string) (textTree, error) {
+	current := t
+	for i, key := range path {
+		switch next := current[key].(type) {
+		case nil:
+			nested := textTree{}
+			current[key] = nested
+			current = nested
+		case textTree:
+			current = next
+		default:
+			return nil, fmt.Errorf("key '%s' is used both for a value and for nested keys", strings.Join(path[:i+1], "."))
+		}
+	}
+
+	return current, nil
+}
+
+// set sets the value on the nested maps of the given path,
+// e.g. []string{"database", "host"} for "database.host".
+func (t textTree) set(path []string, value LazyDecoder) error {
+	parent, err := t.section(path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+
+	key := path[len(path)-1]
+	if _, isMap := parent[key].(textTree); isMap {
+		return fmt.Errorf("key '%s' is used both for a value and for nested keys", strings.Join(path, "."))
+	}
+	parent[key] = value
+
+	return nil
+}
+
+func (t textTree) decoder() LazyDecoder {
+	m := make(map[string]LazyDecoder, len(t))
+	for key, value := range t {
+		switch value := value.(type) {
+		case textTree:
+			m[key] = value.decoder()
+		case LazyDecoder:
+			m[key] = value
+		}
+	}
+
+	return mapDecoder(m)
+}
+
+// textValueDecoder returns a LazyDecoder for values of text formats,
+// which are decoded like environment variables: as YAML scalars,
+// or as comma separated lists when decoded into slices.
+func textValueDecoder(value string, line int, column int) LazyDecoder {
+	scalar := scalarDecoder(value)
+	return func(target any) error {
+		if probe, ok := target.(*originProbe); ok {
+			probe.line = line
+			probe.column = column
+			return nil
+		}
+
+		t := reflect.TypeOf(target)
+		isList := t.Kind() == reflect.Ptr && (t.Elem().Kind() == reflect.Slice || t.Elem().Kind() == reflect.Array)
+		if !isList || t.Elem().Elem().Kind() == reflect.Uint8 {
+			return scalar(target)
+		}
+
+		list := &yaml.Node{Kind: yaml.SequenceNode}
+		if strings.TrimSpace(value) != "" {
+			for _, item := range strings.Split(value, ",") {
+				list.Content = append(list.Content, &yaml.Node{
+					Kind:  yaml.ScalarNode,
+					Value: strings.TrimSpace(item),
+				})
+			}
+		}
+
+		return list.Decode(target)
+	}
+}
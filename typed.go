@@ -0,0 +1,102 @@
+package kparse
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// The functions on this file return the parsed value instead of
+// filling a pointer, so there is no pointer to forget, e.g.:
+//
+//	config, err := kparse.ParseYAMLFileAs[Config]("config.yaml")
+//
+// Go has no type constraint for "any struct", so T is checked before
+// parsing and must be a struct or a slice, array or map of structs.
+
+// ParseYAMLAs parses a YAML file into a new value of type T.
+func ParseYAMLAs[T any](file []byte, opts ...Option) (T, error) {
+	var value T
+	err := checkRootType[T]()
+	if err != nil {
+		return value, err
+	}
+
+	err = ParseYAML(file, &value, opts...)
+	return value, err
+}
+
+// ParseYAMLFileAs parses the YAML file at path into a new value of type T.
+func ParseYAMLFileAs[T any](path string, opts ...Option) (T, error) {
+	var value T
+	err := checkRootType[T]()
+	if err != nil {
+		return value, err
+	}
+
+	err = ParseYAMLFile(path, &value, opts...)
+	return value, err
+}
+
+// ParseJSONAs parses a JSON file into a new value of type T.
+func ParseJSONAs[T any](file []byte, opts ...Option) (T, error) {
+	var value T
+	err := checkRootType[T]()
+	if err != nil {
+		return value, err
+	}
+
+	err = ParseJSON(file, &value, opts...)
+	return value, err
+}
+
+// ParseJSONFileAs parses the JSON file at path into a new value of type T.
+func ParseJSONFileAs[T any](path string, opts ...Option) (T, error) {
+	var value T
+	err := checkRootType[T]()
+	if err != nil {
+		return value, err
+	}
+
+	err = ParseJSONFile(path, &value, opts...)
+	return value, err
+}
+
+// Load parses the file at path into a new value of type T, choosing
+// the format by the file extension: .yaml, .yml or .json.
+func Load[T any](path string, opts ...Option) (T, error) {
+	format, err := formatFromPath(path)
+	if err != nil {
+		var value T
+		return value, err
+	}
+
+	if format == "json" {
+		return ParseJSONFileAs[T](path, opts...)
+	}
+	return ParseYAMLFileAs[T](path, opts...)
+}
+
+// MustLoad works like Load but panics on errors,
+// which is useful for loading configs on startup.
+func MustLoad[T any](path string, opts ...Option) T {
+	value, err := Load[T](path, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func checkRootType[T any]() error {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	root := t
+	for root.Kind() == reflect.Slice || root.Kind() == reflect.Array || root.Kind() == reflect.Map {
+		root = root.Elem()
+	}
+
+	if root.Kind() != reflect.Struct || isSecretType(root) {
+		return fmt.Errorf("expected the type parameter to be a struct or a collection of structs but got: %v", t)
+	}
+
+	return nil
+}
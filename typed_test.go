@@ -0,0 +1,63 @@
+package kparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestTypedParsers(t *testing.T) {
+	type Config struct {
+		Host       string `yaml:"host" json:"host" validate:"required"`
+		MaxRetries int    `yaml:"maxRetries" json:"maxRetries" default:"3"`
+	}
+
+	t.Run("should return the parsed values", func(t *testing.T) {
+		config, err := ParseYAMLAs[Config]([]byte("host: fakeHost"))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config, Config{Host: "fakeHost", MaxRetries: 3})
+
+		configs, err := ParseJSONAs[[]Config]([]byte(`[{"host": "fakeHost"}]`))
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, configs, []Config{{Host: "fakeHost", MaxRetries: 3}})
+	})
+
+	t.Run("should load files by their extension", func(t *testing.T) {
+		dir := t.TempDir()
+		yamlPath := filepath.Join(dir, "config.yml")
+		jsonPath := filepath.Join(dir, "config.json")
+		tt.AssertNoErr(t, os.WriteFile(yamlPath, []byte("host: yamlHost\n"), 0o644))
+		tt.AssertNoErr(t, os.WriteFile(jsonPath, []byte(`{"host": "jsonHost"}`), 0o644))
+
+		config, err := Load[Config](yamlPath)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, config, Config{Host: "yamlHost", MaxRetries: 3})
+
+		config = MustLoad[Config](jsonPath)
+		tt.AssertEqual(t, config, Config{Host: "jsonHost", MaxRetries: 3})
+
+		_, err = Load[Config](filepath.Join(dir, "config.ini"))
+		tt.AssertErrContains(t, err, "unsupported file extension", "config.ini")
+	})
+
+	t.Run("should report errors", func(t *testing.T) {
+		_, err := ParseYAMLAs[Config]([]byte("maxRetries: 5"))
+		tt.AssertErrContains(t, err, "missing required field 'host'")
+
+		_, err = ParseYAMLAs[*Config]([]byte("host: fakeHost"))
+		tt.AssertErrContains(t, err, "struct", "*kparse.Config")
+
+		_, err = ParseJSONFileAs[map[string]int]("config.json")
+		tt.AssertErrContains(t, err, "struct", "map[string]int")
+	})
+
+	t.Run("should panic if MustLoad fails", func(t *testing.T) {
+		defer func() {
+			tt.AssertEqual(t, recover() != nil, true)
+		}()
+
+		MustLoad[Config](filepath.Join(t.TempDir(), "missing.yaml"))
+	})
+}
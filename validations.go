@@ -7,14 +7,17 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-type validatorFactory func(fieldName string, rule string) (Validator, error)
+// ValidatorFactory builds a Validator for a validation expression,
+// e.g. for `validate:"prefix=https://"` it is called with the rule
+// "=https://". The validators receive a pointer to the field value.
+type ValidatorFactory func(fieldName string, rule string) (Validator, error)
 
 type validatorFactoryMapKey struct {
 	Op   string
 	Kind reflect.Kind
 }
 
-var validatorFactoryMap = map[validatorFactoryMapKey]validatorFactory{
+var validatorFactoryMap = map[validatorFactoryMapKey]ValidatorFactory{
 	// range validators are the only ones with no keyword prefix,
 	// so they will match an empty string:
 	{"", reflect.Int}:     newRangeValidator[int],
@@ -38,12 +41,7 @@ var validatorFactoryMap = map[validatorFactoryMapKey]validatorFactory{
 }
 
 func newRangeValidator[T Number](fieldName string, rule string) (_ Validator, err error) {
-	var i int
-	for i < len(rule) && isInequalityChar(rule[i]) {
-		i++
-	}
-
-	op := rule[:i]
+	op, limitStr := splitRule(rule)
 
 	var isValid func(attr T, limit T) bool
 	switch op {
@@ -72,7 +70,7 @@ func newRangeValidator[T Number](fieldName string, rule string) (_ Validator, er
 	}
 
 	var limit T
-	err = yaml.Unmarshal([]byte(rule[i:]), &limit)
+	err = yaml.Unmarshal([]byte(limitStr), &limit)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing number for range validator: '%s', usage: [< | > | <= | >= | =]<number>", rule)
 	}
@@ -94,17 +92,23 @@ func newRangeValidator[T Number](fieldName string, rule string) (_ Validator, er
 	}, nil
 }
 
-func isInequalityChar(c byte) bool {
-	return c == '<' || c == '>' || c == '='
-}
-
-func newLenValidator(fieldName string, rule string) (_ Validator, err error) {
+// splitRule separates the inequality operator from the limit
+// on rules such as ">=10", returning ">=" and "10".
+func splitRule(rule string) (op string, limit string) {
 	var i int
 	for i < len(rule) && isInequalityChar(rule[i]) {
 		i++
 	}
 
-	op := rule[:i]
+	return rule[:i], rule[i:]
+}
+
+func isInequalityChar(c byte) bool {
+	return c == '<' || c == '>' || c == '='
+}
+
+func newLenValidator(fieldName string, rule string) (_ Validator, err error) {
+	op, limitStr := splitRule(rule)
 
 	var isValid func(len int, limit int) bool
 	switch op {
@@ -133,7 +137,7 @@ func newLenValidator(fieldName string, rule string) (_ Validator, err error) {
 	}
 
 	var limit int
-	err = yaml.Unmarshal([]byte(rule[i:]), &limit)
+	err = yaml.Unmarshal([]byte(limitStr), &limit)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing number for length validator: '%s', usage: [< | > | <= | >= | =]<number>", rule)
 	}
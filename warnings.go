@@ -0,0 +1,49 @@
+package kparse
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Warning describes a problem found on a config that doesn't
+// prevent it from being parsed, e.g. the use of a deprecated key.
+type Warning struct {
+	// Path is the key path of the value, e.g. "database.baseUrl"
+	Path    string
+	Message string
+}
+
+// String implements the fmt.Stringer interface.
+func (w Warning) String() string {
+	return w.Path + ": " + w.Message
+}
+
+// WithWarnings collects the warnings found while parsing,
+// the slice pointed by warnings is replaced on every call.
+//
+// It is ignored by NewParser, so it must be passed to each call.
+func WithWarnings(warnings *[]Warning) Option {
+	return func(opts *parseOptions) {
+		opts.warnings = warnings
+	}
+}
+
+// WithLogger logs the warnings found while parsing, by
+// default warnings are only reported by WithWarnings.
+func WithLogger(logger *slog.Logger) Option {
+	return func(opts *parseOptions) {
+		opts.logger = logger
+	}
+}
+
+func (s *parseState) warn(warning Warning) {
+	if s.options.warnings != nil {
+		*s.options.warnings = append(*s.options.warnings, warning)
+	}
+
+	if s.options.logger != nil {
+		s.options.logger.LogAttrs(context.Background(), slog.LevelWarn, "kparse: "+warning.Message,
+			slog.String("key", warning.Path),
+		)
+	}
+}
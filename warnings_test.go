@@ -0,0 +1,108 @@
+package kparse
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestAliasesAndDeprecations(t *testing.T) {
+	type Config struct {
+		BaseURL  string `yaml:"baseURL" aliases:"baseUrl,base_url" validate:"required"`
+		Timeout  int    `yaml:"timeout" deprecated:"timeouts are now set per route"`
+		Database struct {
+			Name string `yaml:"name" aliases:"database" deprecated:"use name"`
+		} `yaml:"database"`
+	}
+
+	tests := []struct {
+		desc               string
+		input              string
+		expectedConfig     Config
+		expectedWarnings   []Warning
+		expectErrToContain []string
+	}{
+		{
+			desc:  "should not warn when using the current keys",
+			input: `baseURL: https://example.com`,
+			expectedConfig: Config{
+				BaseURL: "https://example.com",
+			},
+		},
+		{
+			desc: "should accept values from the aliases with warnings",
+			input: `base_url: https://example.com
+database:
+  database: fakeName
+`,
+			expectedConfig: func() Config {
+				var config Config
+				config.BaseURL = "https://example.com"
+				config.Database.Name = "fakeName"
+				return config
+			}(),
+			expectedWarnings: []Warning{
+				{Path: "base_url", Message: "key is deprecated: use 'baseURL' instead"},
+				{Path: "database.database", Message: "key is deprecated: use name"},
+			},
+		},
+		{
+			desc: "should warn about deprecated keys",
+			input: `baseUrl: https://example.com
+timeout: 10
+`,
+			expectedConfig: Config{
+				BaseURL: "https://example.com",
+				Timeout: 10,
+			},
+			expectedWarnings: []Warning{
+				{Path: "baseUrl", Message: "key is deprecated: use 'baseURL' instead"},
+				{Path: "timeout", Message: "key is deprecated: timeouts are now set per route"},
+			},
+		},
+		{
+			desc: "should report conflicts between keys and aliases",
+			input: `baseURL: https://example.com
+baseUrl: https://example.org
+`,
+			expectErrToContain: []string{"conflicting keys 'baseURL' and 'baseUrl'", "use only 'baseURL'"},
+		},
+		{
+			desc: "should report conflicts between aliases",
+			input: `baseUrl: https://example.com
+base_url: https://example.org
+`,
+			expectErrToContain: []string{"conflicting keys 'baseUrl' and 'base_url'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var config Config
+			var warnings []Warning
+			err := ParseYAML([]byte(test.input), &config, WithWarnings(&warnings), WithStrict(true))
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, config, test.expectedConfig)
+			tt.AssertEqual(t, warnings, test.expectedWarnings)
+		})
+	}
+
+	t.Run("should log the warnings", func(t *testing.T) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+		var config Config
+		err := ParseYAML([]byte(`baseUrl: https://example.com`), &config, WithLogger(logger))
+		tt.AssertNoErr(t, err)
+		for _, substr := range []string{"level=WARN", "key is deprecated", "key=baseUrl"} {
+			tt.AssertEqual(t, strings.Contains(logs.String(), substr), true, logs.String())
+		}
+	})
+}
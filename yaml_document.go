@@ -0,0 +1,438 @@
+package kparse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/vingarcia/structi"
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLDocument keeps the original node tree of a parsed YAML
+// file so changes made to the parsed struct can be written back
+// preserving the comments, key order and quoting of the file.
+type YAMLDocument struct {
+	root yaml.Node
+	opts []Option
+
+	// snapshot holds the values of the struct when it was last
+	// parsed or updated, so we only touch the nodes that changed
+	snapshot *yaml.Node
+}
+
+// ParseYAMLDocumentFile works like ParseYAMLFile but also
+// returns the document for writing the changes back later.
+func ParseYAMLDocumentFile(path string, targetStruct any, opts ...Option) (*YAMLDocument, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]Option{WithSourceName(path)}, opts...)
+	return ParseYAMLDocument(file, targetStruct, opts...)
+}
+
+// ParseYAMLDocument works like ParseYAML but also returns the
+// document for writing the changes back later, e.g.:
+//
+//	doc, err := kparse.ParseYAMLDocument(file, &config)
+//	...
+//	config.MaxRetries = 5
+//	file, err = doc.Update(&config)
+//
+// Files with multiple documents are rejected, since only the first
+// document would be parsed and the others would be lost on Update.
+func ParseYAMLDocument(file []byte, targetStruct any, opts ...Option) (*YAMLDocument, error) {
+	doc := YAMLDocument{opts: opts}
+	decoder := yaml.NewDecoder(bytes.NewReader(file))
+	err := decoder.Decode(&doc.root)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	var next yaml.Node
+	err = decoder.Decode(&next)
+	if err == nil && !isEmptyDocument(&next) {
+		return nil, fmt.Errorf("expected a single YAML document, files with multiple documents can't be updated")
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if doc.root.Kind == 0 {
+		doc.root = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+
+	// The LazyDecoders keep pointers to the nodes of doc.root:
+	var data map[string]LazyDecoder
+	err = doc.root.Decode(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = parseFromMap("yaml", targetStruct, data, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	doc.snapshot, err = structNode(targetStruct)
+	if err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// Update applies to the document the values of the struct that changed
+// since it was parsed or last updated and returns the resulting file.
+//
+// Only the changed values are rewritten, so the comments, key order
+// and the secret references of the unchanged values are preserved,
+// but the file is always re-indented with two spaces.
+//
+// The result is parsed back before being returned, so the document is
+// not changed and an error is returned if the new values are invalid.
+func (d *YAMLDocument) Update(structPtr any) ([]byte, error) {
+	current, err := structNode(structPtr)
+	if err != nil {
+		return nil, err
+	}
+
+	// The results of the first parse, e.g. its origins and warnings,
+	// must not be replaced by the parses made only for checking the values:
+	opts := append(slices.Clip(d.opts), withoutSideEffects())
+
+	// The keys of the file are matched with the same options of the parser,
+	// so aliases and loose keys are updated instead of duplicated:
+	state := defaultParser.newParseState("yaml", opts)
+
+	var updated yaml.Node
+	copyNode(&updated, &d.root)
+	state.applyNodeChanges(reflect.TypeOf(structPtr).Elem(), updated.Content[0], d.snapshot, current)
+
+	output, err := encodeKeepingBlankLines(&updated)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := reflect.New(reflect.TypeOf(structPtr).Elem()).Interface()
+	err = ParseYAML(output, parsed, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("refusing to write invalid config: %w", err)
+	}
+
+	d.root = updated
+	d.snapshot = current
+	return output, nil
+}
+
+// isEmptyDocument reports if the document has no values,
+// e.g. the one after a trailing "---" separator.
+func isEmptyDocument(doc *yaml.Node) bool {
+	if len(doc.Content) == 0 {
+		return true
+	}
+
+	value := doc.Content[0]
+	return len(doc.Content) == 1 && value.Kind == yaml.ScalarNode && value.Tag == "!!null" && value.Value == ""
+}
+
+// withoutSideEffects disables the options that record the results of a
+// call or change anything outside of it, like writing migrated files.
+func withoutSideEffects() Option {
+	return func(opts *parseOptions) {
+		opts.origins = nil
+		opts.warnings = nil
+		opts.logger = nil
+		opts.migratedFilePath = ""
+		opts.setenv = false
+	}
+}
+
+func structNode(structPtr any) (*yaml.Node, error) {
+	v := reflect.ValueOf(structPtr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected non-nil struct pointer but got: %T", structPtr)
+	}
+
+	return valueNode("yaml", v.Elem(), false)
+}
+
+// applyNodeChanges updates the target node with the differences
+// between the before and after nodes, which are built by valueNode.
+//
+// The type t is the type of the values, it is used for finding
+// the keys of struct fields written with aliases on the target.
+func (s *parseState) applyNodeChanges(t reflect.Type, target *yaml.Node, before *yaml.Node, after *yaml.Node) {
+	if nodesEqual(before, after) {
+		return
+	}
+
+	t = documentValueType(t)
+
+	switch {
+	case target.Kind == yaml.MappingNode && before.Kind == yaml.MappingNode && after.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(after.Content); i += 2 {
+			key, afterValue := after.Content[i], after.Content[i+1]
+
+			beforeValue := mappingValue(before, key.Value)
+			if beforeValue != nil && nodesEqual(beforeValue, afterValue) {
+				continue
+			}
+
+			j := s.targetKeyIndex(t, target, key.Value)
+			if j < 0 {
+				target.Content = append(target.Content, key, afterValue)
+				continue
+			}
+
+			if beforeValue == nil {
+				beforeValue = &yaml.Node{}
+			}
+			s.applyNodeChanges(s.documentKeyType(t, key.Value), target.Content[j+1], beforeValue, afterValue)
+		}
+
+		// Keys can only be removed from maps:
+		for i := 0; i+1 < len(before.Content); i += 2 {
+			key := before.Content[i].Value
+			if mappingValue(after, key) != nil {
+				continue
+			}
+
+			if j := s.targetKeyIndex(t, target, key); j >= 0 {
+				target.Content = append(target.Content[:j], target.Content[j+2:]...)
+			}
+		}
+
+	case target.Kind == yaml.SequenceNode && before.Kind == yaml.SequenceNode && after.Kind == yaml.SequenceNode:
+		var itemType reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			itemType = t.Elem()
+		}
+
+		for i, afterItem := range after.Content {
+			if i >= len(target.Content) {
+				target.Content = append(target.Content, afterItem)
+				continue
+			}
+
+			beforeItem := &yaml.Node{}
+			if i < len(before.Content) {
+				beforeItem = before.Content[i]
+			}
+			s.applyNodeChanges(itemType, target.Content[i], beforeItem, afterItem)
+		}
+
+		if len(target.Content) > len(after.Content) {
+			target.Content = target.Content[:len(after.Content)]
+		}
+
+	default:
+		replaceNode(target, after)
+	}
+}
+
+// targetKeyIndex finds the index of a key on the target mapping,
+// the keys of struct fields are also found by their aliases and by the
+// loose or case insensitive matching used by the parser.
+func (s *parseState) targetKeyIndex(t reflect.Type, target *yaml.Node, key string) int {
+	_, tags, found := s.documentField(t, key)
+	if !found {
+		// The keys of maps are always matched exactly:
+		return mappingKeyIndex(target, key)
+	}
+
+	sourceMap := map[string]LazyDecoder{}
+	for i := 0; i+1 < len(target.Content); i += 2 {
+		sourceMap[target.Content[i].Value] = target.Content[i+1].Decode
+	}
+
+	keys := []string{key}
+	if tags["aliases"] != "" {
+		keys = append(keys, strings.Split(tags["aliases"], ",")...)
+	}
+
+	for _, key := range keys {
+		value, sourceKey := s.lookupKey(sourceMap, strings.TrimSpace(key))
+		if value != nil {
+			return mappingKeyIndex(target, sourceKey)
+		}
+	}
+
+	return -1
+}
+
+// documentKeyType returns the type of the values of a key of type t.
+func (s *parseState) documentKeyType(t reflect.Type, key string) reflect.Type {
+	if fieldType, _, found := s.documentField(t, key); found {
+		return fieldType
+	}
+
+	if t != nil && t.Kind() == reflect.Map {
+		return t.Elem()
+	}
+
+	return nil
+}
+
+// documentField finds the struct field written with the given key by
+// valueNode, returning its type and tags.
+func (s *parseState) documentField(t reflect.Type, key string) (reflect.Type, map[string]string, bool) {
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, nil, false
+	}
+
+	info, err := structi.GetStructInfo(t)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	// The nodes of the struct are always written with the yaml tag:
+	for _, field := range info.Fields {
+		if keyFromTag(field.Tags["yaml"]) == key {
+			return field.Type, field.Tags, true
+		}
+	}
+
+	return nil, nil, false
+}
+
+// documentValueType returns the type written by valueNode for
+// values of type t, which skips pointers and Secret types.
+func documentValueType(t reflect.Type) reflect.Type {
+	for t != nil && (t.Kind() == reflect.Ptr || isSecretType(t)) {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		} else {
+			t = unwrapSecretType(t)
+		}
+	}
+	return t
+}
+
+// replaceNode replaces the contents of the target
+// node with the new value, preserving its comments.
+func replaceNode(target *yaml.Node, value *yaml.Node) {
+	style := target.Style
+	if target.Kind != value.Kind || target.Tag != value.Tag {
+		style = value.Style
+	}
+
+	*target = yaml.Node{
+		Kind:        value.Kind,
+		Style:       style,
+		Tag:         value.Tag,
+		Value:       value.Value,
+		Content:     value.Content,
+		HeadComment: target.HeadComment,
+		LineComment: target.LineComment,
+		FootComment: target.FootComment,
+	}
+}
+
+// copyNode makes a deep copy of the node tree, so failed
+// updates don't change the original tree of the document.
+func copyNode(target *yaml.Node, node *yaml.Node) {
+	*target = *node
+	target.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		target.Content[i] = &yaml.Node{}
+		copyNode(target.Content[i], child)
+	}
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	i := mappingKeyIndex(node, key)
+	if i < 0 {
+		return nil
+	}
+	return node.Content[i+1]
+}
+
+func nodesEqual(a *yaml.Node, b *yaml.Node) bool {
+	var aValue, bValue any
+	errA := a.Decode(&aValue)
+	errB := b.Decode(&bValue)
+	return errA == nil && errB == nil && reflect.DeepEqual(aValue, bValue)
+}
+
+// blankLineMarker is added as a comment where the original file had
+// blank lines, since the YAML encoder discards them, and is replaced
+// by an empty line after encoding.
+const blankLineMarker = "#kparse:blank-line"
+
+var blankLineMarkerRegex = regexp.MustCompile(`(?m)^[ \t]*` + blankLineMarker + `$`)
+
+func encodeKeepingBlankLines(root *yaml.Node) ([]byte, error) {
+	var marked yaml.Node
+	copyNode(&marked, root)
+	markBlankLines(&marked)
+
+	output, err := encodeNodeAsYAML(&marked)
+	if err != nil {
+		return nil, err
+	}
+
+	return blankLineMarkerRegex.ReplaceAll(output, nil), nil
+}
+
+func markBlankLines(node *yaml.Node) {
+	for _, child := range node.Content {
+		markBlankLines(child)
+	}
+
+	step := 1
+	if node.Kind == yaml.MappingNode {
+		step = 2
+	} else if node.Kind != yaml.SequenceNode {
+		return
+	}
+
+	// For mappings we compare each key with the previous value
+	// and for sequences each item with the previous item:
+	for i := step; i < len(node.Content); i += step {
+		item := node.Content[i]
+		previousEnd := lastLine(node.Content[i-1])
+		if step == 2 {
+			// The previous value might have been replaced by Update:
+			previousEnd = max(previousEnd, lastLine(node.Content[i-2]))
+		}
+		if item.Line == 0 || previousEnd == 0 {
+			// Nodes added by Update have no line numbers
+			continue
+		}
+
+		firstLine := item.Line
+		if item.HeadComment != "" {
+			firstLine -= strings.Count(item.HeadComment, "\n") + 1
+		}
+
+		if firstLine > previousEnd+1 {
+			item.HeadComment = strings.TrimSuffix(blankLineMarker+"\n"+item.HeadComment, "\n")
+		}
+	}
+}
+
+// lastLine returns the last line of the original file used by the node.
+func lastLine(node *yaml.Node) int {
+	line := node.Line
+	for _, child := range node.Content {
+		line = max(line, lastLine(child))
+	}
+
+	if node.FootComment != "" && line > 0 {
+		line += strings.Count(node.FootComment, "\n") + 1
+	}
+
+	return line
+}
@@ -0,0 +1,252 @@
+package kparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tt "github.com/teamcollab-net/kparse/internal/testtools"
+)
+
+func TestYAMLDocument(t *testing.T) {
+	type Config struct {
+		Host       string            `yaml:"host"`
+		MaxRetries int               `yaml:"maxRetries" default:"3" validate:"<=10"`
+		Password   Secret[string]    `yaml:"password"`
+		Domains    []string          `yaml:"domains"`
+		Labels     map[string]string `yaml:"labels"`
+		Database   struct {
+			Port int    `yaml:"port" default:"5432"`
+			Name string `yaml:"name"`
+		} `yaml:"database"`
+	}
+
+	const file = `# The public host name
+host: 'fakeHost' # without the protocol
+
+password: ${env:KPARSE_TEST_PASSWORD}
+
+domains:
+  - foo.com # the main domain
+  - bar.com
+
+labels:
+  team: core
+  env: prod
+
+# Database settings:
+database:
+  name: fakeName # the database name
+`
+
+	tests := []struct {
+		desc               string
+		edit               func(config *Config)
+		expectedOutput     string
+		expectErrToContain []string
+	}{
+		{
+			desc: "should not change anything if no values changed",
+			edit: func(config *Config) {},
+			expectedOutput: `# The public host name
+host: 'fakeHost' # without the protocol
+
+password: ${env:KPARSE_TEST_PASSWORD}
+
+domains:
+  - foo.com # the main domain
+  - bar.com
+
+labels:
+  team: core
+  env: prod
+
+# Database settings:
+database:
+  name: fakeName # the database name
+`,
+		},
+		{
+			desc: "should only write the changed values",
+			edit: func(config *Config) {
+				config.Host = "newHost"
+				config.MaxRetries = 5
+				config.Domains = []string{"foo.com", "baz.com", "qux.com"}
+				delete(config.Labels, "env")
+				config.Labels["owner"] = "fakeOwner"
+				config.Database.Name = "newName"
+			},
+			expectedOutput: `# The public host name
+host: 'newHost' # without the protocol
+
+password: ${env:KPARSE_TEST_PASSWORD}
+
+domains:
+  - foo.com # the main domain
+  - baz.com
+  - qux.com
+
+labels:
+  team: core
+  owner: fakeOwner
+
+# Database settings:
+database:
+  name: newName # the database name
+maxRetries: 5
+`,
+		},
+		{
+			desc: "should add values missing from the file to their sections",
+			edit: func(config *Config) {
+				config.Domains = config.Domains[:1]
+				config.Database.Port = 5433
+			},
+			expectedOutput: `# The public host name
+host: 'fakeHost' # without the protocol
+
+password: ${env:KPARSE_TEST_PASSWORD}
+
+domains:
+  - foo.com # the main domain
+
+labels:
+  team: core
+  env: prod
+
+# Database settings:
+database:
+  name: fakeName # the database name
+  port: 5433
+`,
+		},
+		{
+			desc: "should refuse to write invalid values",
+			edit: func(config *Config) {
+				config.MaxRetries = 11
+			},
+			expectErrToContain: []string{"invalid config", "MaxRetries", "<= 10"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Setenv("KPARSE_TEST_PASSWORD", "fakePassword")
+
+			var config Config
+			doc, err := ParseYAMLDocument([]byte(file), &config)
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, config.Password.Value(), "fakePassword")
+
+			test.edit(&config)
+
+			output, err := doc.Update(&config)
+			if test.expectErrToContain != nil {
+				tt.AssertErrContains(t, err, test.expectErrToContain...)
+				return
+			}
+			tt.AssertNoErr(t, err)
+			tt.AssertEqual(t, string(output), test.expectedOutput)
+		})
+	}
+
+	t.Run("should keep working after multiple updates", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(path, []byte("host: fakeHost # the host\n"), 0o644)
+		tt.AssertNoErr(t, err)
+
+		var config Config
+		doc, err := ParseYAMLDocumentFile(path, &config)
+		tt.AssertNoErr(t, err)
+
+		config.Host = "newHost"
+		_, err = doc.Update(&config)
+		tt.AssertNoErr(t, err)
+
+		config.MaxRetries = 11
+		_, err = doc.Update(&config)
+		tt.AssertErrContains(t, err, "invalid config")
+
+		config.MaxRetries = 3
+		output, err := doc.Update(&config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, string(output), "host: newHost # the host\n")
+	})
+
+	t.Run("should reject files with multiple documents", func(t *testing.T) {
+		var config Config
+		_, err := ParseYAMLDocument([]byte("host: a\n---\nhost: b\n"), &config)
+		tt.AssertErrContains(t, err, "multiple documents")
+
+		// A trailing document separator has no document after it:
+		doc, err := ParseYAMLDocument([]byte("host: a\n---\n"), &config)
+		tt.AssertNoErr(t, err)
+
+		config.Host = "b"
+		output, err := doc.Update(&config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, string(output), "host: b\n")
+	})
+
+	t.Run("should not replace the results of the first parse", func(t *testing.T) {
+		migratedPath := filepath.Join(t.TempDir(), "migrated.yaml")
+
+		var config struct {
+			BaseURL string `yaml:"baseURL" aliases:"baseUrl"`
+			Port    int    `yaml:"port"`
+		}
+		var warnings []Warning
+		var origins Origins
+		doc, err := ParseYAMLDocument([]byte("baseUrl: http://old.com\nport: 80\n"), &config,
+			WithWarnings(&warnings),
+			WithOrigins(&origins),
+			WithMigrations("version", map[int]Migration{
+				1: func(raw map[string]any) error { return nil },
+			}),
+			WithMigratedFile(migratedPath),
+		)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, len(warnings), 1)
+		tt.AssertEqual(t, origins["port"].Line, 2)
+
+		config.Port = 8080
+		_, err = doc.Update(&config)
+		tt.AssertNoErr(t, err)
+
+		tt.AssertEqual(t, len(warnings), 1)
+		tt.AssertEqual(t, origins["port"].Line, 2)
+
+		_, err = os.Stat(migratedPath)
+		tt.AssertEqual(t, os.IsNotExist(err), true)
+	})
+
+	t.Run("should update keys written with aliases", func(t *testing.T) {
+		var config struct {
+			BaseURL string `yaml:"baseURL" aliases:"baseUrl"`
+			Proxy   struct {
+				Port int `yaml:"port" aliases:"proxyPort"`
+			} `yaml:"proxy"`
+		}
+		doc, err := ParseYAMLDocument([]byte("baseUrl: http://old.com # deprecated\nproxy:\n  proxyPort: 80\n"), &config)
+		tt.AssertNoErr(t, err)
+
+		config.BaseURL = "http://new.com"
+		config.Proxy.Port = 8080
+		output, err := doc.Update(&config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, string(output), "baseUrl: http://new.com # deprecated\nproxy:\n  proxyPort: 8080\n")
+	})
+
+	t.Run("should update keys matched by the loose keys option", func(t *testing.T) {
+		var config struct {
+			MaxRetries int `yaml:"maxRetries"`
+		}
+		doc, err := ParseYAMLDocument([]byte("max_retries: 3\n"), &config, WithLooseKeys(true))
+		tt.AssertNoErr(t, err)
+
+		config.MaxRetries = 5
+		output, err := doc.Update(&config)
+		tt.AssertNoErr(t, err)
+		tt.AssertEqual(t, string(output), "max_retries: 5\n")
+	})
+}